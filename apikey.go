@@ -1,8 +1,49 @@
 package jump
 
-import "github.com/gdbu/jump/apikeys"
+import (
+	"time"
+
+	"github.com/gdbu/jump/apikeys"
+)
 
 // GetAPIKeysByUser will return the api keys for a user
 func (j *Jump) GetAPIKeysByUser(userID string) (as []*apikeys.APIKey, err error) {
 	return j.api.GetByUser(userID)
 }
+
+// RotateAPIKey will issue a replacement api key, the original remains valid for the grace period
+func (j *Jump) RotateAPIKey(keyID string, gracePeriod time.Duration) (key string, err error) {
+	return j.api.Rotate(keyID, gracePeriod)
+}
+
+// SetAPIKeyExpiration will set the unix timestamp an api key expires at, zero removes the expiration
+func (j *Jump) SetAPIKeyExpiration(keyID string, expiresAt int64) (err error) {
+	return j.api.SetExpiration(keyID, expiresAt)
+}
+
+// SetAPIKeyExpiryWarning will set how far ahead of expiration the api-key-expiring event is emitted
+func (j *Jump) SetAPIKeyExpiryWarning(d time.Duration) {
+	j.api.SetExpiryWarning(d)
+}
+
+// ListUnusedAPIKeys will return the api keys which have not been used since the provided time
+func (j *Jump) ListUnusedAPIKeys(since time.Time) (as []*apikeys.APIKey, err error) {
+	return j.api.ListUnusedSince(since)
+}
+
+// SetAPIKeyAllowedCIDRs will set the CIDR ranges an api key may be used from, an empty list allows any IP
+func (j *Jump) SetAPIKeyAllowedCIDRs(keyID string, cidrs []string) (err error) {
+	return j.api.SetAllowedCIDRs(keyID, cidrs)
+}
+
+// SetAPIKeyRequireSigning will set whether or not an api key only accepts signed requests
+// Note: See the signing package for the client signer
+func (j *Jump) SetAPIKeyRequireSigning(keyID string, requireSigning bool) (err error) {
+	return j.api.SetRequireSigning(keyID, requireSigning)
+}
+
+// SetSigningMaxSkew will set the maximum difference allowed between the timestamp of a signed request and the server clock
+// Note: The default is signing.DefaultMaxSkew
+func (j *Jump) SetSigningMaxSkew(maxSkew time.Duration) {
+	j.verifier.SetMaxSkew(maxSkew)
+}
@@ -1,14 +1,21 @@
 package apikeys
 
 import (
+	"fmt"
+	"net"
+	"time"
+
 	"github.com/gdbu/errors"
+	"github.com/gdbu/jump/permissions"
 	"github.com/mojura/mojura"
 )
 
-func makeAPIKey(userID, name, key string) (a APIKey) {
+func makeAPIKey(userID, name string, p ParsedKey) (a APIKey) {
 	a.UserID = userID
 	a.Name = name
-	a.Key = key
+	a.Key = p.ID
+	a.Environment = p.Environment
+	a.SecretHash = hashSecret(p.Secret)
 	return
 }
 
@@ -19,9 +26,29 @@ type APIKey struct {
 	UserID string `json:"userID"`
 
 	Name string `json:"name"`
-	Key  string `json:"key"`
+	// Key is the public identifier of the API key
+	// Note: Keys created prior to prefixed keys store the full legacy key value
+	Key string `json:"key"`
+
+	Environment string `json:"environment,omitempty"`
+	// SecretHash is the SHA-256 hash of the key secret, the secret itself is never stored
+	SecretHash string `json:"secretHash,omitempty"`
+
+	// Scope restricts the key to specific resources and actions, a nil scope carries the full permissions of the user
+	Scope *Scope `json:"scope,omitempty"`
+
+	// ExpiresAt is the unix timestamp the key expires at, zero represents no expiration
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+	// ExpiryWarnedAt is the unix timestamp the upcoming expiration event was emitted at
+	ExpiryWarnedAt int64 `json:"expiryWarnedAt,omitempty"`
+
+	// AllowedCIDRs restricts the client IPs the key may be used from, an empty list allows any IP
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
 
-	UpdatedAt int64 `json:"updatedAt"`
+	// RequireSigning rejects the key when it is presented directly, only signed requests are accepted
+	RequireSigning bool `json:"requireSigning,omitempty"`
+
+	Usage
 }
 
 // Validate will validate an API key
@@ -35,12 +62,96 @@ func (a *APIKey) Validate() (err error) {
 		errs.Push(ErrInvalidName)
 	}
 
+	if a.Scope != nil {
+		errs.Push(a.Scope.Validate())
+	}
+
+	for _, cidr := range a.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs.Push(fmt.Errorf("%v: <%s>", ErrInvalidCIDR, cidr))
+		}
+	}
+
 	return errs.Err()
 }
 
+// IsAllowedIP will return whether or not the key may be used from the provided client IP
+func (a *APIKey) IsAllowedIP(ip string) bool {
+	if len(a.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, cidr := range a.AllowedCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsExpired will return whether or not the key has expired as of the provided time
+func (a *APIKey) IsExpired(now time.Time) bool {
+	if a.ExpiresAt == 0 {
+		return false
+	}
+
+	return now.Unix() >= a.ExpiresAt
+}
+
+// SigningKey will return the HMAC key used to verify requests signed by the key
+// Note: The signing key is the stored secret hash, see the signing package for the tradeoff
+func (a *APIKey) SigningKey() []byte {
+	return []byte(a.SecretHash)
+}
+
+// IsLegacy will return whether or not the API key predates prefixed keys
+// Note: Sanitized legacy keys no longer hold the full key value and are not reported as legacy
+func (a *APIKey) IsLegacy() bool {
+	return len(a.SecretHash) == 0 && isLegacyKey(a.Key)
+}
+
+// SanitizedID will return an identifier of the key which is safe to log or emit
+// Note: Legacy keys store the full key value as their Key, the entry ID is returned instead.
+// Management funcs (e.g. Remove or Rotate) accept the sanitized ID of a legacy key as its key ID.
+func (a *APIKey) SanitizedID() string {
+	if a.IsLegacy() {
+		return a.ID
+	}
+
+	return a.Key
+}
+
+// Allows will return whether or not the key's scope permits an action on a resource key
+// Note: Unscoped keys permit everything, the user's permissions must still be checked
+func (a *APIKey) Allows(resourceKey string, action permissions.Action) bool {
+	if a.Scope == nil {
+		return true
+	}
+
+	return a.Scope.Allows(resourceKey, action)
+}
+
+func (a *APIKey) sanitize() {
+	// Legacy keys store the full key value, it is replaced by the sanitized ID before the secret hash is cleared
+	a.Key = a.SanitizedID()
+	a.SecretHash = ""
+}
+
 // GetRelationships will get the associated relationship IDs
 func (a *APIKey) GetRelationships() (r mojura.Relationships) {
 	r.Append(a.Key)
 	r.Append(a.UserID)
+	r.Append(formatExpiresAtBucket(a.ExpiresAt))
 	return
 }
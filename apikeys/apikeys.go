@@ -2,9 +2,12 @@ package apikeys
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/gdbu/errors"
-	"github.com/gdbu/uuid"
+	"github.com/gdbu/jump/events"
 	"github.com/mojura/mojura"
 	"github.com/mojura/mojura/filters"
 )
@@ -18,19 +21,52 @@ const (
 	ErrInvalidUserID = errors.Error("invalid user id, cannot be empty")
 	// ErrInvalidName is returned when a APIKey's name is empty
 	ErrInvalidName = errors.Error("invalid name, cannot be empty")
+	// ErrInvalidAPIKey is returned when an api key is malformed or fails checksum verification
+	ErrInvalidAPIKey = errors.Error("invalid api key format")
+	// ErrInvalidEnvironment is returned when an environment contains characters other than lowercase letters and digits
+	ErrInvalidEnvironment = errors.Error("invalid environment, must only contain lowercase letters and digits")
+	// ErrEnvironmentMismatch is returned when an api key was issued for a different environment
+	ErrEnvironmentMismatch = errors.Error("api key was issued for a different environment")
+	// ErrAPIKeyExpired is returned when an expired api key is presented
+	ErrAPIKeyExpired = errors.Error("api key has expired")
+	// ErrInvalidGracePeriod is returned when a rotation grace period is negative
+	ErrInvalidGracePeriod = errors.Error("invalid grace period, cannot be negative")
+	// ErrInvalidCIDR is returned when an allowed CIDR cannot be parsed
+	ErrInvalidCIDR = errors.Error("invalid CIDR")
+	// ErrIPNotAllowed is returned when an api key is presented from an IP outside of its allowlist
+	ErrIPNotAllowed = errors.Error("api key is not allowed from this IP address")
+	// ErrSigningRequired is returned when a key which requires signing is presented directly
+	ErrSigningRequired = errors.Error("api key requires signed requests")
+	// ErrLegacySigningUnsupported is returned when requiring signing for a legacy key, legacy keys cannot sign requests
+	ErrLegacySigningUnsupported = errors.Error("legacy api keys cannot require signing, rotate the key first")
 )
 
 const (
-	relationshipKeys  = "keys"
-	relationshipUsers = "users"
+	// EventAPIKeyRotated is emitted with the sanitized original key after it has been replaced by Rotate
+	EventAPIKeyRotated = "api-key-rotated"
+	// EventAPIKeyExpiring is emitted with the sanitized key once it is within the expiry warning window
+	EventAPIKeyExpiring = "api-key-expiring"
+	// EventAPIKeyExpired is emitted with the sanitized key after it has expired and been removed
+	EventAPIKeyExpired = "api-key-expired"
+)
+
+const (
+	// DefaultEnvironment is the environment embedded within newly created keys
+	DefaultEnvironment = "live"
+)
+
+const (
+	relationshipKeys           = "keys"
+	relationshipUsers          = "users"
+	relationshipExpiresAtHours = "expiresAtHours"
 )
 
 var (
-	relationships = []string{relationshipKeys, relationshipUsers}
+	relationships = []string{relationshipKeys, relationshipUsers, relationshipExpiresAtHours}
 )
 
 // New will return a new instance of APIKeys
-func New(opts mojura.Opts) (ap *APIKeys, err error) {
+func New(opts mojura.Opts, e *events.Controller) (ap *APIKeys, err error) {
 	opts.Name = "apikeys"
 
 	var a APIKeys
@@ -38,8 +74,19 @@ func New(opts mojura.Opts) (ap *APIKeys, err error) {
 		return
 	}
 
-	// Create UUID generator
-	a.gen = uuid.NewGenerator()
+	a.out = mojura.NewLogger()
+	a.env = DefaultEnvironment
+	a.events = e
+	a.usage = newUsageTracker()
+	a.isMirror = opts.IsMirror
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	if !opts.IsMirror {
+		// Sweep keys which expired while stopped before serving, then start the expiration sweep loop
+		a.sweep()
+		a.wg.Add(1)
+		go a.loop()
+	}
 
 	// Assign pointer to created instance of APIKeys
 	ap = &a
@@ -48,15 +95,39 @@ func New(opts mojura.Opts) (ap *APIKeys, err error) {
 
 // APIKeys manages the apiKeys service
 type APIKeys struct {
-	m *mojura.Mojura[*APIKey]
+	out mojura.Logger
+	m   *mojura.Mojura[*APIKey]
+
+	env    string
+	events *events.Controller
+	usage  *usageTracker
 
-	gen *uuid.Generator
+	isMirror bool
+
+	warnMux       sync.RWMutex
+	expiryWarning time.Duration
+
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
 }
 
-// New will create a new apiKey and return the associated ID
+// New will create a new apiKey and return the full key value
+// Note: The full key is only available at creation, only its public ID and a hash of its secret are stored
 func (a *APIKeys) New(userID, name string) (key string, err error) {
-	uuid := a.gen.New()
-	apiKey := makeAPIKey(userID, name, uuid.String())
+	return a.new(userID, name, nil)
+}
+
+// NewScoped will create a new apiKey restricted to the provided scope and return the full key value
+// Note: The full key is only available at creation, only its public ID and a hash of its secret are stored
+func (a *APIKeys) NewScoped(userID, name string, scope Scope) (key string, err error) {
+	return a.new(userID, name, &scope)
+}
+
+func (a *APIKeys) new(userID, name string, scope *Scope) (key string, err error) {
+	p := newParsedKey(a.env)
+	apiKey := makeAPIKey(userID, name, p)
+	apiKey.Scope = scope
 	if err = apiKey.Validate(); err != nil {
 		return
 	}
@@ -65,61 +136,415 @@ func (a *APIKeys) New(userID, name string) (key string, err error) {
 		return
 	}
 
-	key = apiKey.Key
+	key = p.String()
 	return
 }
 
-// Get will return the APIKey entry associated with the provided api key value
+// Get will return the APIKey entry associated with the provided full api key value
+// Note: Malformed keys are rejected before the database is accessed
 func (a *APIKeys) Get(key string) (apiKey *APIKey, err error) {
-	err = a.m.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
-		apiKey, err = a.get(txn, key)
+	if isLegacyKey(key) {
+		return a.getLegacy(key)
+	}
+
+	var p ParsedKey
+	if p, err = Parse(key); err != nil {
 		return
-	})
+	}
+
+	if p.Environment != a.env {
+		err = ErrEnvironmentMismatch
+		return
+	}
+
+	if err = a.m.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		apiKey, err = a.get(txn, p.ID)
+		return
+	}); err != nil {
+		return
+	}
+
+	if apiKey.IsLegacy() || !isSecretMatch(p.Secret, apiKey.SecretHash) {
+		apiKey = nil
+		err = ErrAPIKeyNotFound
+		return
+	}
+
+	if apiKey.IsExpired(time.Now()) {
+		apiKey = nil
+		err = ErrAPIKeyExpired
+		return
+	}
+
+	if apiKey.RequireSigning {
+		apiKey = nil
+		err = ErrSigningRequired
+		return
+	}
 
 	return
 }
 
+// GetForSigning will return the APIKey entry used to verify a request signed by the provided public key ID
+// Note: The returned entry is not sanitized, its SigningKey must never be exposed
+func (a *APIKeys) GetForSigning(keyID string) (apiKey *APIKey, err error) {
+	if err = a.m.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		apiKey, err = a.get(txn, keyID)
+		return
+	}); err != nil {
+		return
+	}
+
+	if apiKey.IsLegacy() {
+		// Legacy keys have no secret hash to sign with
+		apiKey = nil
+		err = ErrAPIKeyNotFound
+		return
+	}
+
+	if apiKey.Environment != a.env {
+		apiKey = nil
+		err = ErrEnvironmentMismatch
+		return
+	}
+
+	if apiKey.IsExpired(time.Now()) {
+		apiKey = nil
+		err = ErrAPIKeyExpired
+		return
+	}
+
+	return
+}
+
+// GetByID will return the APIKey entry associated with the provided public key ID
+func (a *APIKeys) GetByID(keyID string) (apiKey *APIKey, err error) {
+	if err = a.m.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		apiKey, err = a.getManaged(txn, keyID)
+		return
+	}); err != nil {
+		return
+	}
+
+	apiKey.sanitize()
+	return
+}
+
 // GetByUser will return the APIKeys associated with the provided user id
 func (a *APIKeys) GetByUser(userID string) (apiKeys []*APIKey, err error) {
 	filter := filters.Match(relationshipUsers, userID)
 	opts := mojura.NewFilteringOpts(filter)
-	apiKeys, _, err = a.m.GetFiltered(opts)
+	if apiKeys, _, err = a.m.GetFiltered(opts); err != nil {
+		return
+	}
+
+	for _, apiKey := range apiKeys {
+		apiKey.sanitize()
+	}
+
 	return
 }
 
-// UpdateName will edit an APIKey's name
-func (a *APIKeys) UpdateName(apiKey, name string) (err error) {
+// UpdateName will edit an APIKey's name by key ID
+func (a *APIKeys) UpdateName(keyID, name string) (err error) {
 	err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
-		return a.updateName(txn, apiKey, name)
+		return a.updateName(txn, keyID, name)
 	})
 
 	return
 }
 
-// Remove will delete an apiKey
-func (a *APIKeys) Remove(apiKey string) (removed *APIKey, err error) {
-	err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
-		removed, err = a.remove(txn, apiKey)
+// Remove will delete an apiKey by key ID
+func (a *APIKeys) Remove(keyID string) (removed *APIKey, err error) {
+	if err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		removed, err = a.remove(txn, keyID)
+		return
+	}); err != nil {
 		return
+	}
+
+	removed.sanitize()
+	return
+}
+
+// SetAllowedCIDRs will set the CIDR ranges an apiKey (by key ID) may be used from, an empty list allows any IP
+func (a *APIKeys) SetAllowedCIDRs(keyID string, cidrs []string) (err error) {
+	err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		return a.setAllowedCIDRs(txn, keyID, cidrs)
 	})
 
 	return
 }
 
-// Close will close the apiKeys service
+// SetRequireSigning will set whether or not an apiKey (by key ID) only accepts signed requests
+// Note: Legacy keys cannot be used to sign requests, ErrLegacySigningUnsupported is returned
+func (a *APIKeys) SetRequireSigning(keyID string, requireSigning bool) (err error) {
+	err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		return a.setRequireSigning(txn, keyID, requireSigning)
+	})
+
+	return
+}
+
+// SetExpiration will set the unix timestamp an apiKey expires at by key ID, zero removes the expiration
+func (a *APIKeys) SetExpiration(keyID string, expiresAt int64) (err error) {
+	err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		return a.setExpiration(txn, keyID, expiresAt)
+	})
+
+	return
+}
+
+// Rotate will issue a replacement for an apiKey (by key ID) and return the full replacement key value
+// The replacement inherits the name, scope, allowed CIDRs and signing requirement of the original. The original key will continue to
+// work until the grace period has passed, unless it was already set to expire sooner.
+func (a *APIKeys) Rotate(keyID string, gracePeriod time.Duration) (key string, err error) {
+	if gracePeriod < 0 {
+		err = ErrInvalidGracePeriod
+		return
+	}
+
+	p := newParsedKey(a.env)
+
+	var original *APIKey
+	if err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		original, err = a.rotate(txn, keyID, p, gracePeriod)
+		return
+	}); err != nil {
+		return
+	}
+
+	original.sanitize()
+	a.notify(EventAPIKeyRotated, original)
+	key = p.String()
+	return
+}
+
+// SetExpiryWarning will set how far ahead of expiration the api-key-expiring event is emitted, zero disables warnings
+func (a *APIKeys) SetExpiryWarning(d time.Duration) {
+	a.warnMux.Lock()
+	defer a.warnMux.Unlock()
+	a.expiryWarning = d
+}
+
+// SetEnvironment will set the environment embedded within newly created keys (e.g. "live" or "test")
+// Note: Keys issued for other environments are rejected by Get and GetForSigning
+func (a *APIKeys) SetEnvironment(env string) (err error) {
+	if !isValidEnvironment(env) {
+		return ErrInvalidEnvironment
+	}
+
+	a.env = env
+	return
+}
+
+// RecordUsage will record a request made with an apiKey (by key ID) from the provided IP address
+// Note: Usage is buffered in memory and written in batches, mirrors do not record usage
+func (a *APIKeys) RecordUsage(keyID, ip string) {
+	if a.isMirror {
+		// Mirrors cannot write, usage is recorded by the source
+		return
+	}
+
+	a.usage.record(keyID, ip, time.Now())
+}
+
+// ListUnusedSince will return the apiKeys which have not been used since the provided time
+// Note: Keys which have never been used are included when they were created before the provided time
+func (a *APIKeys) ListUnusedSince(t time.Time) (apiKeys []*APIKey, err error) {
+	since := t.Unix()
+	err = a.m.ForEach(func(_ string, apiKey *APIKey) (err error) {
+		lastUsedAt := apiKey.LastUsedAt
+		if lastUsedAt == 0 {
+			lastUsedAt = apiKey.CreatedAt
+		}
+
+		if lastUsedAt >= since {
+			return
+		}
+
+		apiKey.sanitize()
+		apiKeys = append(apiKeys, apiKey)
+		return
+	}, nil)
+
+	return
+}
+
+// Flush will write all pending usage to the store
+func (a *APIKeys) Flush() (err error) {
+	pending := a.usage.drain()
+	if len(pending) == 0 {
+		return
+	}
+
+	if err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		return a.flush(txn, pending, time.Now())
+	}); err != nil {
+		// Retain the usage so it is written by the next flush
+		a.usage.restore(pending)
+	}
+
+	return
+}
+
+// Sweep will remove all expired keys and emit expiration warnings for keys which are about to expire
+func (a *APIKeys) Sweep() (err error) {
+	now := time.Now()
+	if err = a.warnExpiring(now); err != nil {
+		return
+	}
+
+	var removed []*APIKey
+	if err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		removed, err = a.removeExpired(txn, now)
+		return
+	}); err != nil {
+		return
+	}
+
+	for _, apiKey := range removed {
+		apiKey.sanitize()
+		a.notify(EventAPIKeyExpired, apiKey)
+	}
+
+	return
+}
+
+// Close will stop the expiration sweep loop and close the apiKeys service
 func (a *APIKeys) Close() (err error) {
-	return a.m.Close()
+	a.cancel()
+	a.wg.Wait()
+
+	var errs errors.ErrorList
+	if !a.isMirror {
+		errs.Push(a.Flush())
+	}
+
+	errs.Push(a.m.Close())
+	return errs.Err()
+}
+
+func (a *APIKeys) loop() {
+	defer a.wg.Done()
+	sweep := time.NewTicker(time.Minute)
+	defer sweep.Stop()
+	flush := time.NewTicker(UsageFlushInterval)
+	defer flush.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-sweep.C:
+			a.sweep()
+		case <-flush.C:
+			if err := a.Flush(); err != nil {
+				a.out.Error(fmt.Sprintf("error flushing api key usage: %v", err))
+			}
+		}
+	}
+}
+
+func (a *APIKeys) sweep() {
+	if err := a.Sweep(); err != nil {
+		a.out.Error(fmt.Sprintf("error sweeping expired api keys: %v", err))
+	}
+}
+
+func (a *APIKeys) notify(key string, value interface{}) {
+	evt := events.MakeEvent(key, value)
+	a.events.New(evt)
+}
+
+func (a *APIKeys) getExpiryWarning() time.Duration {
+	a.warnMux.RLock()
+	defer a.warnMux.RUnlock()
+	return a.expiryWarning
+}
+
+func (a *APIKeys) warnExpiring(now time.Time) (err error) {
+	var warning time.Duration
+	if warning = a.getExpiryWarning(); warning <= 0 {
+		return
+	}
+
+	var warned []*APIKey
+	if err = a.m.Transaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		warned, err = a.markExpiring(txn, now, now.Add(warning))
+		return
+	}); err != nil {
+		return
+	}
+
+	for _, apiKey := range warned {
+		apiKey.sanitize()
+		a.notify(EventAPIKeyExpiring, apiKey)
+	}
+
+	return
+}
+
+// getLegacy will return the APIKey entry for a key created prior to prefixed keys
+func (a *APIKeys) getLegacy(key string) (apiKey *APIKey, err error) {
+	if err = a.m.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*APIKey]) (err error) {
+		apiKey, err = a.get(txn, key)
+		return
+	}); err != nil {
+		return
+	}
+
+	if !apiKey.IsLegacy() {
+		apiKey = nil
+		err = ErrAPIKeyNotFound
+		return
+	}
+
+	if apiKey.IsExpired(time.Now()) {
+		// Legacy keys expire once rotated, see Rotate
+		apiKey = nil
+		err = ErrAPIKeyExpired
+		return
+	}
+
+	if apiKey.RequireSigning {
+		apiKey = nil
+		err = ErrSigningRequired
+		return
+	}
+
+	return
 }
 
-func (a *APIKeys) get(txn *mojura.Transaction[*APIKey], key string) (apiKey *APIKey, err error) {
-	filter := filters.Match(relationshipKeys, key)
+func (a *APIKeys) get(txn *mojura.Transaction[*APIKey], keyID string) (apiKey *APIKey, err error) {
+	filter := filters.Match(relationshipKeys, keyID)
 	opts := mojura.NewFilteringOpts(filter)
 	return txn.GetFirst(opts)
 }
 
-func (a *APIKeys) updateName(txn *mojura.Transaction[*APIKey], apiKey, name string) (err error) {
+// getManaged will return the APIKey entry for a key ID, legacy keys are also matched by their sanitized ID
+// Note: This must never be used to authenticate, the sanitized ID of a legacy key is not a secret
+func (a *APIKeys) getManaged(txn *mojura.Transaction[*APIKey], keyID string) (apiKey *APIKey, err error) {
+	if apiKey, err = a.get(txn, keyID); err != mojura.ErrEntryNotFound {
+		return
+	}
+
+	var legacy *APIKey
+	if legacy, err = txn.Get(keyID); err != nil {
+		return
+	}
+
+	if !legacy.IsLegacy() {
+		err = mojura.ErrEntryNotFound
+		return
+	}
+
+	return legacy, nil
+}
+
+func (a *APIKeys) updateName(txn *mojura.Transaction[*APIKey], keyID, name string) (err error) {
 	var match *APIKey
-	if match, err = a.get(txn, apiKey); err != nil {
+	if match, err = a.getManaged(txn, keyID); err != nil {
 		return
 	}
 
@@ -128,9 +553,150 @@ func (a *APIKeys) updateName(txn *mojura.Transaction[*APIKey], apiKey, name stri
 	return
 }
 
-func (a *APIKeys) remove(txn *mojura.Transaction[*APIKey], apiKey string) (removed *APIKey, err error) {
+func (a *APIKeys) setAllowedCIDRs(txn *mojura.Transaction[*APIKey], keyID string, cidrs []string) (err error) {
+	var match *APIKey
+	if match, err = a.getManaged(txn, keyID); err != nil {
+		return
+	}
+
+	match.AllowedCIDRs = cidrs
+	if err = match.Validate(); err != nil {
+		return
+	}
+
+	_, err = txn.Put(match.ID, match)
+	return
+}
+
+func (a *APIKeys) setRequireSigning(txn *mojura.Transaction[*APIKey], keyID string, requireSigning bool) (err error) {
+	var match *APIKey
+	if match, err = a.getManaged(txn, keyID); err != nil {
+		return
+	}
+
+	if requireSigning && match.IsLegacy() {
+		err = ErrLegacySigningUnsupported
+		return
+	}
+
+	match.RequireSigning = requireSigning
+	_, err = txn.Put(match.ID, match)
+	return
+}
+
+func (a *APIKeys) setExpiration(txn *mojura.Transaction[*APIKey], keyID string, expiresAt int64) (err error) {
+	var match *APIKey
+	if match, err = a.getManaged(txn, keyID); err != nil {
+		return
+	}
+
+	match.ExpiresAt = expiresAt
+	match.ExpiryWarnedAt = 0
+	_, err = txn.Put(match.ID, match)
+	return
+}
+
+func (a *APIKeys) rotate(txn *mojura.Transaction[*APIKey], keyID string, p ParsedKey, gracePeriod time.Duration) (original *APIKey, err error) {
+	if original, err = a.getManaged(txn, keyID); err != nil {
+		return
+	}
+
+	replacement := makeAPIKey(original.UserID, original.Name, p)
+	replacement.Scope = original.Scope
+	replacement.AllowedCIDRs = original.AllowedCIDRs
+	replacement.RequireSigning = original.RequireSigning
+	if err = replacement.Validate(); err != nil {
+		return
+	}
+
+	if _, err = txn.New(&replacement); err != nil {
+		return
+	}
+
+	expiresAt := time.Now().Add(gracePeriod).Unix()
+	if original.ExpiresAt == 0 || original.ExpiresAt > expiresAt {
+		original.ExpiresAt = expiresAt
+	}
+
+	_, err = txn.Put(original.ID, original)
+	return
+}
+
+// markExpiring will set the expiry warned at timestamp for all keys expiring before the provided deadline
+func (a *APIKeys) markExpiring(txn *mojura.Transaction[*APIKey], now, deadline time.Time) (warned []*APIKey, err error) {
+	filter := filters.LessThanOrEqualTo(relationshipExpiresAtHours, formatHourBucket(deadline.Unix()))
+	opts := mojura.NewFilteringOpts(filter)
+
+	var expiring []*APIKey
+	if expiring, _, err = txn.GetFiltered(opts); err != nil {
+		return
+	}
+
+	for _, apiKey := range expiring {
+		if apiKey.ExpiryWarnedAt > 0 || apiKey.ExpiresAt > deadline.Unix() || apiKey.IsExpired(now) {
+			continue
+		}
+
+		apiKey.ExpiryWarnedAt = now.Unix()
+		if _, err = txn.Put(apiKey.ID, apiKey); err != nil {
+			return
+		}
+
+		warned = append(warned, apiKey)
+	}
+
+	return
+}
+
+func (a *APIKeys) removeExpired(txn *mojura.Transaction[*APIKey], now time.Time) (removed []*APIKey, err error) {
+	filter := filters.LessThanOrEqualTo(relationshipExpiresAtHours, formatHourBucket(now.Unix()))
+	opts := mojura.NewFilteringOpts(filter)
+
+	var expired []*APIKey
+	if expired, _, err = txn.GetFiltered(opts); err != nil {
+		return
+	}
+
+	for _, apiKey := range expired {
+		if !apiKey.IsExpired(now) {
+			continue
+		}
+
+		if _, err = txn.Delete(apiKey.ID); err != nil {
+			return
+		}
+
+		removed = append(removed, apiKey)
+	}
+
+	return
+}
+
+func (a *APIKeys) flush(txn *mojura.Transaction[*APIKey], pending map[string]*pendingUsage, now time.Time) (err error) {
+	for keyID, p := range pending {
+		var match *APIKey
+		switch match, err = a.get(txn, keyID); err {
+		case nil:
+		case mojura.ErrEntryNotFound:
+			// Key was removed before usage was written, nothing to record
+			err = nil
+			continue
+		default:
+			return
+		}
+
+		match.Usage.merge(p, now)
+		if _, err = txn.Put(match.ID, match); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (a *APIKeys) remove(txn *mojura.Transaction[*APIKey], keyID string) (removed *APIKey, err error) {
 	var match *APIKey
-	if match, err = a.get(txn, apiKey); err != nil {
+	if match, err = a.getManaged(txn, keyID); err != nil {
 		return
 	}
 
@@ -0,0 +1,488 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdbu/jump/events"
+	"github.com/gdbu/jump/permissions"
+	"github.com/gdbu/jump/signing"
+	"github.com/mojura/mojura"
+)
+
+const (
+	testUser1 = "TEST_USER_1"
+)
+
+func TestAPIKeys(t *testing.T) {
+	var (
+		a   *APIKeys
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if a, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var key string
+	if key, err = a.New(testUser1, "primary"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, "jump_live_") {
+		t.Fatalf("invalid key prefix, expected <%s> and received <%s>", "jump_live_", key)
+	}
+
+	var apiKey *APIKey
+	if apiKey, err = a.Get(key); err != nil {
+		t.Fatal(err)
+	} else if apiKey.UserID != testUser1 {
+		t.Fatalf("invalid user ID, expected <%s> and received <%s>", testUser1, apiKey.UserID)
+	}
+
+	if strings.Contains(key, apiKey.SecretHash) || strings.HasSuffix(key, apiKey.Key) {
+		t.Fatal("expected stored key to not contain the secret")
+	}
+
+	p, _ := Parse(key)
+	p.Secret = randomBase62(secretLength)
+	if _, err = a.Get(p.String()); err != ErrAPIKeyNotFound {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrAPIKeyNotFound, err)
+	}
+
+	var apiKeys []*APIKey
+	if apiKeys, err = a.GetByUser(testUser1); err != nil {
+		t.Fatal(err)
+	} else if len(apiKeys) != 1 || len(apiKeys[0].SecretHash) > 0 {
+		t.Fatalf("invalid api keys, expected one sanitized key and received %+v", apiKeys)
+	}
+}
+
+func TestAPIKeys_Rotate(t *testing.T) {
+	var (
+		a   *APIKeys
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if a, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var original string
+	if original, err = a.New(testUser1, "primary"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := Parse(original)
+
+	var replacement string
+	if replacement, err = a.Rotate(p.ID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Get(original); err != nil {
+		t.Fatalf("expected original key to work during grace period, received <%v>", err)
+	}
+
+	var apiKey *APIKey
+	if apiKey, err = a.Get(replacement); err != nil {
+		t.Fatal(err)
+	} else if apiKey.Name != "primary" || apiKey.ExpiresAt != 0 {
+		t.Fatalf("invalid replacement, expected inherited name and no expiration and received %+v", apiKey)
+	}
+
+	if err = a.SetExpiration(p.ID, time.Now().Add(-time.Second).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Get(original); err != ErrAPIKeyExpired {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrAPIKeyExpired, err)
+	}
+
+	if err = a.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.GetByID(p.ID); err != mojura.ErrEntryNotFound {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", mojura.ErrEntryNotFound, err)
+	}
+}
+
+func TestAPIKeys_Rotate_legacy(t *testing.T) {
+	var (
+		a   *APIKeys
+		err error
+	)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	if a, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	// Legacy keys are stored as the plaintext key value without a secret hash
+	legacyKey := "0123456789abcdef0123456789abcdef"
+	legacy := APIKey{Key: legacyKey, UserID: testUser1, Name: "legacy"}
+	if _, err = a.m.New(&legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Get(legacyKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Rotate(legacyKey, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Get(legacyKey); err != ErrAPIKeyExpired {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrAPIKeyExpired, err)
+	}
+}
+
+func TestAPIKeys_legacy_sanitized(t *testing.T) {
+	var (
+		a   *APIKeys
+		err error
+	)
+
+	payloads := make(chan string, 8)
+	e := events.New()
+	e.Subscribe(func(evt events.Event) {
+		bs, err := json.Marshal(evt.Value)
+		if err != nil {
+			t.Error(err)
+		}
+
+		payloads <- evt.Key + ":" + string(bs)
+	}, EventAPIKeyRotated, EventAPIKeyExpiring, EventAPIKeyExpired)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	if a, err = New(opts, e); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	a.SetExpiryWarning(time.Hour)
+
+	// Legacy keys are stored as the plaintext key value without a secret hash
+	legacyKey := "0123456789abcdef0123456789abcdef"
+	legacy := APIKey{Key: legacyKey, UserID: testUser1, Name: "legacy"}
+	if _, err = a.m.New(&legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Rotate(legacyKey, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	var apiKeys []*APIKey
+	if apiKeys, err = a.GetByUser(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var unused []*APIKey
+	if unused, err = a.ListUnusedSince(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var sanitizedID string
+	for _, apiKey := range append(apiKeys, unused...) {
+		if apiKey.Key == legacyKey {
+			t.Fatal("expected the legacy key to be sanitized")
+		}
+
+		if apiKey.ExpiresAt > 0 {
+			sanitizedID = apiKey.Key
+		}
+	}
+
+	// The sanitized ID of a legacy key is accepted by management funcs, but not for authentication
+	if err = a.SetExpiration(sanitizedID, time.Now().Add(time.Minute*30).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Get(sanitizedID); err == nil {
+		t.Fatal("expected the sanitized ID to be rejected as a key")
+	}
+
+	if err = a.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.SetExpiration(sanitizedID, time.Now().Add(-time.Minute).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{EventAPIKeyRotated, EventAPIKeyExpiring, EventAPIKeyExpired} {
+		select {
+		case payload := <-payloads:
+			if strings.Contains(payload, legacyKey) {
+				t.Fatalf("invalid payload, expected the legacy key to be sanitized and received %s", payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s event", key)
+		}
+	}
+}
+
+func TestAPIKeys_RecordUsage(t *testing.T) {
+	var (
+		a   *APIKeys
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if a, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var key string
+	if key, err = a.New(testUser1, "primary"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := Parse(key)
+	a.RecordUsage(p.ID, "10.0.0.1")
+	a.RecordUsage(p.ID, "10.0.0.2")
+	if err = a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var apiKeys []*APIKey
+	if apiKeys, err = a.GetByUser(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := apiKeys[0].Usage
+	switch {
+	case usage.TotalRequests != 2:
+		t.Fatalf("invalid total requests, expected <%d> and received <%d>", 2, usage.TotalRequests)
+	case usage.LastUsedIP != "10.0.0.2":
+		t.Fatalf("invalid last used IP, expected <%s> and received <%s>", "10.0.0.2", usage.LastUsedIP)
+	case usage.RequestsSince(time.Now()) != 2:
+		t.Fatalf("invalid daily requests, expected <%d> and received <%d>", 2, usage.RequestsSince(time.Now()))
+	}
+
+	if apiKeys, err = a.ListUnusedSince(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if len(apiKeys) != 0 {
+		t.Fatalf("invalid unused keys, expected none and received %d", len(apiKeys))
+	}
+
+	if apiKeys, err = a.ListUnusedSince(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if len(apiKeys) != 1 {
+		t.Fatalf("invalid unused keys, expected <%d> and received <%d>", 1, len(apiKeys))
+	}
+}
+
+func TestUsageTracker_restore(t *testing.T) {
+	u := newUsageTracker()
+	now := time.Now()
+	u.record("key", "10.0.0.1", now.Add(-time.Minute))
+	drained := u.drain()
+
+	// Usage recorded while the failed flush was in progress
+	u.record("key", "10.0.0.2", now)
+	u.restore(drained)
+
+	pending := u.drain()
+	p, ok := pending["key"]
+	switch {
+	case !ok:
+		t.Fatal("expected restored usage to be pending")
+	case p.lastUsedIP != "10.0.0.2" || p.lastUsedAt != now.Unix():
+		t.Fatalf("invalid last use, expected the most recent use and received %s at %d", p.lastUsedIP, p.lastUsedAt)
+	case p.daily[now.UTC().Format(dayFormat)] != 2:
+		t.Fatalf("invalid daily requests, expected <%d> and received %v", 2, p.daily)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p := newParsedKey(DefaultEnvironment)
+	key := p.String()
+
+	parsed, err := Parse(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed != p {
+		t.Fatalf("invalid parsed key, expected %+v and received %+v", p, parsed)
+	}
+
+	// Flip the final character to break the checksum
+	last := key[len(key)-1]
+	replacement := byte('0')
+	if last == replacement {
+		replacement = '1'
+	}
+
+	invalid := []string{
+		"",
+		"jump_live_abc",
+		key[:len(key)-1] + string(replacement),
+		strings.Replace(key, "jump_", "pmuj_", 1),
+	}
+
+	for _, key := range invalid {
+		if _, err = Parse(key); err != ErrInvalidAPIKey {
+			t.Fatalf("invalid error for <%s>, expected <%v> and received <%v>", key, ErrInvalidAPIKey, err)
+		}
+	}
+}
+
+func TestScope_Allows(t *testing.T) {
+	s := NewScope(permissions.ActionRead|permissions.ActionWrite, "posts::*", "comments")
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		resourceKey string
+		action      permissions.Action
+		expected    bool
+	}{
+		{resourceKey: "posts::1", action: permissions.ActionRead, expected: true},
+		{resourceKey: "posts::1", action: permissions.ActionWrite, expected: true},
+		{resourceKey: "posts::1", action: permissions.ActionDelete, expected: false},
+		{resourceKey: "comments", action: permissions.ActionRead, expected: true},
+		{resourceKey: "comments::1", action: permissions.ActionRead, expected: false},
+		{resourceKey: "users::1", action: permissions.ActionRead, expected: false},
+	}
+
+	for _, tc := range tcs {
+		if allowed := s.Allows(tc.resourceKey, tc.action); allowed != tc.expected {
+			t.Fatalf("invalid result for <%s> / %d, expected %v and received %v", tc.resourceKey, tc.action, tc.expected, allowed)
+		}
+	}
+}
+
+func TestAPIKey_IsAllowedIP(t *testing.T) {
+	type testcase struct {
+		cidrs    []string
+		ip       string
+		expected bool
+	}
+
+	tcs := []testcase{
+		{ip: "203.0.113.5", expected: true},
+		{cidrs: []string{"203.0.113.0/24"}, ip: "203.0.113.5", expected: true},
+		{cidrs: []string{"203.0.113.0/24"}, ip: "198.51.100.7", expected: false},
+		{cidrs: []string{"203.0.113.0/24", "2001:db8::/32"}, ip: "2001:db8::1", expected: true},
+		{cidrs: []string{"203.0.113.0/24"}, ip: "not-an-ip", expected: false},
+	}
+
+	for _, tc := range tcs {
+		a := APIKey{AllowedCIDRs: tc.cidrs}
+		if ok := a.IsAllowedIP(tc.ip); ok != tc.expected {
+			t.Fatalf("invalid result for %s within %v, expected <%v> and received <%v>", tc.ip, tc.cidrs, tc.expected, ok)
+		}
+	}
+
+	a := APIKey{UserID: testUser1, Name: "primary", AllowedCIDRs: []string{"203.0.113.0"}}
+	if err := a.Validate(); err == nil {
+		t.Fatal("expected invalid CIDR to fail validation")
+	}
+}
+
+func TestAPIKeys_RequireSigning(t *testing.T) {
+	var (
+		a   *APIKeys
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if a, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var key string
+	if key, err = a.New(testUser1, "primary"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := Parse(key)
+	if err = a.SetRequireSigning(p.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Get(key); err != ErrSigningRequired {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrSigningRequired, err)
+	}
+
+	var apiKey *APIKey
+	if apiKey, err = a.GetForSigning(p.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(apiKey.SigningKey()) != string(signing.DeriveKey(p.Secret)) {
+		t.Fatal("expected stored signing key to match the key derived by the client signer")
+	}
+
+	if err = a.SetEnvironment("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.GetForSigning(p.ID); err != ErrEnvironmentMismatch {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrEnvironmentMismatch, err)
+	}
+
+	if err = a.SetEnvironment(DefaultEnvironment); err != nil {
+		t.Fatal(err)
+	}
+
+	legacyKey := "0123456789abcdef0123456789abcdef"
+	legacy := APIKey{Key: legacyKey, UserID: testUser1, Name: "legacy"}
+	if _, err = a.m.New(&legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = a.SetRequireSigning(legacyKey, true); err != ErrLegacySigningUnsupported {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrLegacySigningUnsupported, err)
+	}
+
+	if _, err = a.Get(legacyKey); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+)
+
+const (
+	keyPrefix    = "jump"
+	keySeparator = "_"
+
+	idLength       = 16
+	secretLength   = 32
+	checksumLength = 6
+)
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Parse will parse and verify the checksum of an API key without touching the database
+// Keys are formatted as jump_<env>_<id>_<secret><checksum>
+func Parse(key string) (p ParsedKey, err error) {
+	parts := strings.Split(key, keySeparator)
+	if len(parts) != 4 || parts[0] != keyPrefix {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	if !isValidEnvironment(parts[1]) {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	if len(parts[2]) != idLength || !isBase62(parts[2]) {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	tail := parts[3]
+	if len(tail) != secretLength+checksumLength || !isBase62(tail) {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	p.Environment = parts[1]
+	p.ID = parts[2]
+	p.Secret = tail[:secretLength]
+	if tail[secretLength:] != newChecksum(p.body()) {
+		err = ErrInvalidAPIKey
+		return
+	}
+
+	return
+}
+
+func newParsedKey(env string) (p ParsedKey) {
+	p.Environment = env
+	p.ID = randomBase62(idLength)
+	p.Secret = randomBase62(secretLength)
+	return
+}
+
+// ParsedKey represents the components of an API key
+type ParsedKey struct {
+	Environment string
+	// ID is the public identifier of the key
+	ID string
+	// Secret is the private portion of the key, it is never stored
+	Secret string
+}
+
+// String will return the full API key
+func (p ParsedKey) String() string {
+	body := p.body()
+	return body + newChecksum(body)
+}
+
+func (p ParsedKey) body() string {
+	return strings.Join([]string{keyPrefix, p.Environment, p.ID, p.Secret}, keySeparator)
+}
+
+// isLegacyKey will return whether or not a key matches the UUID format used prior to prefixed keys
+func isLegacyKey(key string) (ok bool) {
+	if len(key) < 24 || len(key) > 40 {
+		return
+	}
+
+	for _, c := range key {
+		switch {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'f':
+		case c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isValidEnvironment(env string) (ok bool) {
+	if len(env) == 0 {
+		return
+	}
+
+	for _, c := range env {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isBase62(str string) (ok bool) {
+	for _, c := range str {
+		if !strings.ContainsRune(base62, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func randomBase62(n int) string {
+	out := make([]byte, 0, n)
+	buf := make([]byte, n*2)
+	for len(out) < n {
+		// Note: crypto/rand.Read never returns an error
+		rand.Read(buf)
+		for _, b := range buf {
+			// Reject values which would bias the distribution
+			if b >= 248 {
+				continue
+			}
+
+			if out = append(out, base62[b%62]); len(out) == n {
+				break
+			}
+		}
+	}
+
+	return string(out)
+}
+
+func newChecksum(body string) string {
+	sum := crc32.ChecksumIEEE([]byte(body))
+	out := make([]byte, checksumLength)
+	for i := checksumLength - 1; i >= 0; i-- {
+		out[i] = base62[sum%62]
+		sum /= 62
+	}
+
+	return string(out)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func isSecretMatch(secret, secretHash string) bool {
+	hashed := hashSecret(secret)
+	return subtle.ConstantTimeCompare([]byte(hashed), []byte(secretHash)) == 1
+}
@@ -0,0 +1,65 @@
+package apikeys
+
+import (
+	"path"
+
+	"github.com/gdbu/errors"
+	"github.com/gdbu/jump/permissions"
+)
+
+const (
+	// ErrInvalidScopeResources is returned when a scope has no resource patterns
+	ErrInvalidScopeResources = errors.Error("invalid scope resources, cannot be empty")
+	// ErrInvalidScopeActions is returned when a scope has no actions
+	ErrInvalidScopeActions = errors.Error("invalid scope actions, cannot be empty")
+)
+
+// NewScope will return a new scope for the provided actions and resource key patterns
+func NewScope(actions permissions.Action, resources ...string) (s Scope) {
+	s.Actions = actions
+	s.Resources = resources
+	return
+}
+
+// Scope restricts an API key to a set of resources and actions
+type Scope struct {
+	// Resources is a list of resource key patterns, see path.Match for syntax (e.g. "posts::*")
+	Resources []string `json:"resources"`
+	// Actions is the mask of actions the key may perform on matching resources
+	Actions permissions.Action `json:"actions"`
+}
+
+// Validate will validate a scope
+func (s *Scope) Validate() (err error) {
+	var errs errors.ErrorList
+	if len(s.Resources) == 0 {
+		errs.Push(ErrInvalidScopeResources)
+	}
+
+	if s.Actions == 0 {
+		errs.Push(ErrInvalidScopeActions)
+	}
+
+	for _, pattern := range s.Resources {
+		if _, err = path.Match(pattern, ""); err != nil {
+			errs.Push(err)
+		}
+	}
+
+	return errs.Err()
+}
+
+// Allows will return whether or not the scope permits an action on a resource key
+func (s *Scope) Allows(resourceKey string, action permissions.Action) (ok bool) {
+	if !s.Actions.HasAll(action) {
+		return
+	}
+
+	for _, pattern := range s.Resources {
+		if ok, _ = path.Match(pattern, resourceKey); ok {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,130 @@
+package apikeys
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// UsageFlushInterval is the interval pending usage is written to the store
+	UsageFlushInterval = time.Second * 30
+	// UsageRetentionDays is the number of days daily request counts are retained
+	UsageRetentionDays = 30
+)
+
+const dayFormat = "2006-01-02"
+
+// Usage represents the usage statistics of an api key
+type Usage struct {
+	// LastUsedAt is the unix timestamp the key was last used at
+	LastUsedAt int64 `json:"lastUsedAt,omitempty"`
+	// LastUsedIP is the IP address the key was last used from
+	LastUsedIP string `json:"lastUsedIP,omitempty"`
+	// TotalRequests is the number of requests made with the key
+	TotalRequests int64 `json:"totalRequests,omitempty"`
+	// DailyRequests are the request counts keyed by UTC day (YYYY-MM-DD) for the retention window
+	DailyRequests map[string]int64 `json:"dailyRequests,omitempty"`
+}
+
+// RequestsSince will return the number of requests made from the provided day onward
+func (u *Usage) RequestsSince(t time.Time) (n int64) {
+	since := t.UTC().Format(dayFormat)
+	for day, count := range u.DailyRequests {
+		if day >= since {
+			n += count
+		}
+	}
+
+	return
+}
+
+func (u *Usage) merge(p *pendingUsage, now time.Time) {
+	if p.lastUsedAt > u.LastUsedAt {
+		u.LastUsedAt = p.lastUsedAt
+		u.LastUsedIP = p.lastUsedIP
+	}
+
+	if u.DailyRequests == nil {
+		u.DailyRequests = make(map[string]int64, len(p.daily))
+	}
+
+	for day, count := range p.daily {
+		u.DailyRequests[day] += count
+		u.TotalRequests += count
+	}
+
+	u.trim(now)
+}
+
+// trim will remove the daily request counts which are outside of the retention window
+func (u *Usage) trim(now time.Time) {
+	cutoff := now.UTC().AddDate(0, 0, -UsageRetentionDays).Format(dayFormat)
+	for day := range u.DailyRequests {
+		if day < cutoff {
+			delete(u.DailyRequests, day)
+		}
+	}
+}
+
+type pendingUsage struct {
+	lastUsedAt int64
+	lastUsedIP string
+	daily      map[string]int64
+}
+
+func newUsageTracker() *usageTracker {
+	var u usageTracker
+	u.pending = make(map[string]*pendingUsage)
+	return &u
+}
+
+// usageTracker buffers api key usage in memory so requests do not incur a write
+type usageTracker struct {
+	mux     sync.Mutex
+	pending map[string]*pendingUsage
+}
+
+func (u *usageTracker) record(keyID, ip string, now time.Time) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	p, ok := u.pending[keyID]
+	if !ok {
+		p = &pendingUsage{daily: make(map[string]int64, 1)}
+		u.pending[keyID] = p
+	}
+
+	p.lastUsedAt = now.Unix()
+	p.lastUsedIP = ip
+	p.daily[now.UTC().Format(dayFormat)]++
+}
+
+// drain will return and reset the pending usage
+func (u *usageTracker) drain() (pending map[string]*pendingUsage) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	pending = u.pending
+	u.pending = make(map[string]*pendingUsage, len(pending))
+	return
+}
+
+// restore will merge previously drained usage back into the pending usage, used when a flush fails
+func (u *usageTracker) restore(drained map[string]*pendingUsage) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	for keyID, d := range drained {
+		p, ok := u.pending[keyID]
+		if !ok {
+			u.pending[keyID] = d
+			continue
+		}
+
+		if d.lastUsedAt > p.lastUsedAt {
+			p.lastUsedAt = d.lastUsedAt
+			p.lastUsedIP = d.lastUsedIP
+		}
+
+		for day, n := range d.daily {
+			p.daily[day] += n
+		}
+	}
+}
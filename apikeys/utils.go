@@ -0,0 +1,17 @@
+package apikeys
+
+import "fmt"
+
+// formatExpiresAtBucket will format an expiration unix timestamp as a sortable hour bucket
+// Note: Keys without an expiration return an empty bucket and are not indexed
+func formatExpiresAtBucket(expiresAt int64) (bucket string) {
+	if expiresAt == 0 {
+		return
+	}
+
+	return formatHourBucket(expiresAt)
+}
+
+func formatHourBucket(unix int64) (bucket string) {
+	return fmt.Sprintf("%012d", unix/3600)
+}
@@ -34,6 +34,24 @@ func (j *Jump) Login(ctx *httpserve.Context, email, password string) (userID str
 	return
 }
 
+// LoginWithBearer will attempt to login with a provided email and password combo
+// If successful, an opaque bearer token will be returned to represent the session
+func (j *Jump) LoginWithBearer(ctx *httpserve.Context, email, password string) (userID, bearer string, err error) {
+	if userID, err = j.usrs.MatchEmail(email, password); err != nil {
+		return
+	}
+
+	if bearer, err = j.NewBearerSession(ctx, userID); err != nil {
+		return
+	}
+
+	if err = j.setLastLoggedInAt(userID, time.Now().Unix()); err != nil {
+		return
+	}
+
+	return
+}
+
 // NewSSO will create a new SSO session
 func (j *Jump) NewSSO(ctx context.Context, email string) (loginCode string, err error) {
 	var u *users.User
@@ -91,27 +109,33 @@ func (j *Jump) SSOMultiLogin(ctx *httpserve.Context, loginCode string, ttl time.
 }
 
 // Logout is the logout handler
+// Note: This should be placed after NewSetUserIDMW, the session which authenticated the request is removed
 func (j *Jump) Logout(ctx *httpserve.Context) (err error) {
 	userID := ctx.Get("userID")
 	if len(userID) == 0 {
 		return ErrAlreadyLoggedOut
 	}
 
-	var key, token string
-	if key, err = getCookieValue(ctx.Request(), CookieKey); err != nil {
+	// The session resolved by the authenticator chain is used, the request key/token pair
+	// is no longer valid when the session was rotated while authenticating
+	var sessionID string
+	if sessionID, err = getContextSessionID(ctx); err != nil {
 		return
 	}
 
-	if token, err = getCookieValue(ctx.Request(), CookieToken); err != nil {
+	if err = j.sess.RemoveByID(sessionID); err != nil {
 		return
 	}
 
-	if err = j.sess.Remove(key, token); err != nil {
+	ctx.Put("sessionID", "")
+
+	if ctx.Get("authMethod") == AuthMethodBearer {
+		// Bearer tokens are held by the client, there are no cookies to unset
 		return
 	}
 
-	keyC := unsetCookie(ctx.Request().URL.Host, CookieKey, key)
-	tokenC := unsetCookie(ctx.Request().URL.Host, CookieToken, token)
+	keyC := j.cookies.unsetCookie(j.cookies.keyName())
+	tokenC := j.cookies.unsetCookie(j.cookies.tokenName())
 
 	http.SetCookie(ctx.Writer(), &keyC)
 	http.SetCookie(ctx.Writer(), &tokenC)
@@ -0,0 +1,59 @@
+package jump
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/vroomy/httpserve"
+)
+
+func TestJump_Logout_rotation(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.GET("/logout", j.NewSetUserIDMW(false, false), func(ctx *httpserve.Context) {
+			if err := j.Logout(ctx); err != nil {
+				ctx.WriteJSON(500, err)
+				return
+			}
+
+			ctx.WriteNoContent()
+		})
+	})
+
+	key, token, err := j.sess.New(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Privilege changes rotate the session on its next use, before the logout handler is reached
+	if err = j.sess.MarkForRotation(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequest("GET", baseURL+"/logout", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: CookieKey, Value: key})
+	req.AddCookie(&http.Cookie{Name: CookieToken, Value: token})
+
+	var res *http.Response
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != 204 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 204, res.StatusCode)
+	}
+
+	ss, err := j.sess.GetByUserID(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ss) > 0 {
+		t.Fatalf("invalid sessions, expected none and received %d", len(ss))
+	}
+}
@@ -0,0 +1,271 @@
+package jump
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gdbu/errors"
+	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/sessions"
+	"github.com/gdbu/jump/signing"
+)
+
+const (
+	// ErrNoCredentials is returned by an Authenticator when a request does not contain its credentials
+	ErrNoCredentials = errors.Error("no credentials provided")
+	// ErrAuthenticatorExists is returned when registering an authenticator with a name which is already registered
+	ErrAuthenticatorExists = errors.Error("authenticator with this name already exists")
+	// ErrAuthenticatorNotFound is returned when referencing an authenticator which has not been registered
+	ErrAuthenticatorNotFound = errors.Error("authenticator not found")
+)
+
+const (
+	// AuthenticatorSigned authenticates requests signed by an API key, see the signing package
+	AuthenticatorSigned = "signed"
+	// AuthenticatorAPIKeyQuery authenticates requests by the apiKey query string parameter
+	AuthenticatorAPIKeyQuery = "apiKeyQuery"
+	// AuthenticatorAPIKeyHeader authenticates requests by the X-Api-Key header
+	AuthenticatorAPIKeyHeader = "apiKeyHeader"
+	// AuthenticatorBearer authenticates requests by a session bearer token
+	AuthenticatorBearer = "bearer"
+	// AuthenticatorSession authenticates requests by session cookies
+	AuthenticatorSession = "session"
+	// AuthenticatorBasic authenticates requests by HTTP Basic email and password
+	// Note: Basic is registered but not part of the default chain, every request incurs a password hash comparison
+	AuthenticatorBasic = "basic"
+)
+
+// DefaultAuthChain is the default order authenticators are attempted in
+var DefaultAuthChain = []string{
+	AuthenticatorSigned,
+	AuthenticatorAPIKeyQuery,
+	AuthenticatorAPIKeyHeader,
+	AuthenticatorBearer,
+	AuthenticatorSession,
+}
+
+// Authenticator resolves the principal of a request
+type Authenticator interface {
+	// Name will return the unique name of the authenticator
+	Name() string
+	// Authenticate will return the user ID of the request
+	// ErrNoCredentials is returned when the request does not contain credentials for the authenticator,
+	// the next authenticator within the chain is then attempted. Any other error ends the chain.
+	Authenticate(ctx *httpserve.Context) (userID string, err error)
+}
+
+// AmbientAuthenticator is an Authenticator which may report its credentials as ambient
+// Ambient credentials are attached by browsers automatically (e.g. cookies or cached HTTP Basic credentials),
+// requests authenticated by them are CSRF checked, see NewCSRFMW. Authenticators which do not implement
+// AmbientAuthenticator are exempt from CSRF checks.
+type AmbientAuthenticator interface {
+	Authenticator
+	// IsAmbient will return whether or not the credentials of the authenticator are sent ambiently by browsers
+	IsAmbient() bool
+}
+
+// NewAuthenticator will return an Authenticator for the provided name and func
+// Note: The credentials are treated as explicitly provided (e.g. a header set by the client), see NewAmbientAuthenticator
+func NewAuthenticator(name string, fn func(ctx *httpserve.Context) (userID string, err error)) Authenticator {
+	return &authenticator{name: name, fn: fn}
+}
+
+// NewAmbientAuthenticator will return an Authenticator for the provided name and func whose credentials are
+// sent ambiently by browsers, requests it authenticates are CSRF checked
+func NewAmbientAuthenticator(name string, fn func(ctx *httpserve.Context) (userID string, err error)) Authenticator {
+	return &authenticator{name: name, fn: fn, ambient: true}
+}
+
+type authenticator struct {
+	name    string
+	fn      func(ctx *httpserve.Context) (userID string, err error)
+	ambient bool
+}
+
+func (a *authenticator) Name() string {
+	return a.name
+}
+
+func (a *authenticator) IsAmbient() bool {
+	return a.ambient
+}
+
+func (a *authenticator) Authenticate(ctx *httpserve.Context) (userID string, err error) {
+	return a.fn(ctx)
+}
+
+// RegisterAuthenticator will register an authenticator and append it to the end of the chain
+func (j *Jump) RegisterAuthenticator(a Authenticator) (err error) {
+	j.authMux.Lock()
+	defer j.authMux.Unlock()
+	if _, ok := j.authenticators[a.Name()]; ok {
+		return fmt.Errorf("%v: <%s>", ErrAuthenticatorExists, a.Name())
+	}
+
+	j.authenticators[a.Name()] = a
+	j.authChain = append(j.authChain, a.Name())
+	return
+}
+
+// SetAuthChain will set the order authenticators (by name) are attempted in
+// Note: Registered authenticators which are omitted are disabled
+func (j *Jump) SetAuthChain(names ...string) (err error) {
+	j.authMux.Lock()
+	defer j.authMux.Unlock()
+	for _, name := range names {
+		if _, ok := j.authenticators[name]; !ok {
+			return fmt.Errorf("%v: <%s>", ErrAuthenticatorNotFound, name)
+		}
+	}
+
+	j.authChain = append([]string{}, names...)
+	return
+}
+
+func (j *Jump) registerDefaultAuthenticators() {
+	j.authenticators = make(map[string]Authenticator)
+	j.authenticators[AuthenticatorSigned] = NewAuthenticator(AuthenticatorSigned, j.authenticateSigned)
+	j.authenticators[AuthenticatorAPIKeyQuery] = NewAuthenticator(AuthenticatorAPIKeyQuery, j.authenticateAPIKeyQuery)
+	j.authenticators[AuthenticatorAPIKeyHeader] = NewAuthenticator(AuthenticatorAPIKeyHeader, j.authenticateAPIKeyHeader)
+	j.authenticators[AuthenticatorBearer] = NewAuthenticator(AuthenticatorBearer, j.authenticateBearer)
+	j.authenticators[AuthenticatorSession] = NewAmbientAuthenticator(AuthenticatorSession, j.authenticateSession)
+	j.authenticators[AuthenticatorBasic] = NewAmbientAuthenticator(AuthenticatorBasic, j.authenticateBasic)
+	j.authChain = append([]string{}, DefaultAuthChain...)
+}
+
+// isAmbientAuthenticator will return whether or not an authenticator (by name) sends its credentials ambiently
+func (j *Jump) isAmbientAuthenticator(name string) bool {
+	j.authMux.RLock()
+	defer j.authMux.RUnlock()
+	a, ok := j.authenticators[name].(AmbientAuthenticator)
+	return ok && a.IsAmbient()
+}
+
+// getAuthChain will return the authenticators of the chain, restricted to the allowed names (if any)
+func (j *Jump) getAuthChain(allowed []string) (as []Authenticator) {
+	j.authMux.RLock()
+	defer j.authMux.RUnlock()
+	for _, name := range j.authChain {
+		if len(allowed) > 0 && !slices.Contains(allowed, name) {
+			continue
+		}
+
+		as = append(as, j.authenticators[name])
+	}
+
+	return
+}
+
+// authenticate will attempt each authenticator of the chain until one finds credentials within the request
+func (j *Jump) authenticate(ctx *httpserve.Context, allowed []string) (userID string, err error) {
+	for _, a := range j.getAuthChain(allowed) {
+		switch userID, err = a.Authenticate(ctx); err {
+		case nil:
+			ctx.Put("authenticator", a.Name())
+			if len(ctx.Get("authMethod")) == 0 {
+				ctx.Put("authMethod", a.Name())
+			}
+
+			return
+		case ErrNoCredentials:
+		default:
+			err = fmt.Errorf("error authenticating with %s: %v", a.Name(), err)
+			return
+		}
+	}
+
+	err = ErrNoCredentials
+	return
+}
+
+func (j *Jump) authenticateSigned(ctx *httpserve.Context) (userID string, err error) {
+	req := ctx.Request()
+	if !signing.IsSigned(req) {
+		err = ErrNoCredentials
+		return
+	}
+
+	var keyID string
+	if userID, keyID, err = j.getUserIDFromSignedRequest(req, j.getClientIP(req)); err != nil {
+		return
+	}
+
+	ctx.Put("authMethod", AuthMethodSigned)
+	ctx.Put("apiKeyID", keyID)
+	return
+}
+
+func (j *Jump) authenticateAPIKeyQuery(ctx *httpserve.Context) (userID string, err error) {
+	return j.authenticateAPIKey(ctx, getAPIKeyFromQuery(ctx.Request()))
+}
+
+func (j *Jump) authenticateAPIKeyHeader(ctx *httpserve.Context) (userID string, err error) {
+	return j.authenticateAPIKey(ctx, getAPIKeyFromHeader(ctx.Request()))
+}
+
+func (j *Jump) authenticateAPIKey(ctx *httpserve.Context, apiKey string) (userID string, err error) {
+	if len(apiKey) == 0 {
+		err = ErrNoCredentials
+		return
+	}
+
+	var keyID string
+	if userID, keyID, err = j.getUserIDFromAPIKey(apiKey, j.getClientIP(ctx.Request())); err != nil {
+		return
+	}
+
+	ctx.Put("authMethod", AuthMethodAPIKey)
+	ctx.Put("apiKeyID", keyID)
+	return
+}
+
+func (j *Jump) authenticateBearer(ctx *httpserve.Context) (userID string, err error) {
+	bearer, ok := getBearerToken(ctx.Request())
+	if !ok {
+		err = ErrNoCredentials
+		return
+	}
+
+	var key, token string
+	if key, token, err = sessions.ParseBearer(bearer); err != nil {
+		return
+	}
+
+	if userID, err = j.getUserIDFromSession(ctx, AuthMethodBearer, key, token); err != nil {
+		return
+	}
+
+	ctx.Put("authMethod", AuthMethodBearer)
+	return
+}
+
+func (j *Jump) authenticateSession(ctx *httpserve.Context) (userID string, err error) {
+	var key, token string
+	if key, token, err = j.getSessionCookies(ctx.Request()); err != nil {
+		err = ErrNoCredentials
+		return
+	}
+
+	if userID, err = j.getUserIDFromSession(ctx, AuthMethodSession, key, token); err != nil {
+		return
+	}
+
+	ctx.Put("authMethod", AuthMethodSession)
+	return
+}
+
+func (j *Jump) authenticateBasic(ctx *httpserve.Context) (userID string, err error) {
+	email, password, ok := ctx.Request().BasicAuth()
+	if !ok {
+		err = ErrNoCredentials
+		return
+	}
+
+	if userID, err = j.usrs.MatchEmail(email, password); err != nil {
+		return
+	}
+
+	ctx.Put("authMethod", AuthMethodBasic)
+	return
+}
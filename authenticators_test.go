@@ -0,0 +1,221 @@
+package jump
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vroomy/httpserve"
+)
+
+// newTestHeaderAuthenticator will return an authenticator resolving the value of a request header as the user ID
+func newTestHeaderAuthenticator(name, header string, ambient bool) Authenticator {
+	fn := func(ctx *httpserve.Context) (userID string, err error) {
+		if userID = ctx.Request().Header.Get(header); len(userID) == 0 {
+			err = ErrNoCredentials
+		}
+
+		return
+	}
+
+	if ambient {
+		return NewAmbientAuthenticator(name, fn)
+	}
+
+	return NewAuthenticator(name, fn)
+}
+
+// doTestRequest will perform a request with the provided headers and return the status code and body
+func doTestRequest(t *testing.T, method, url string, headers map[string]string) (statusCode int, body string) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	var res *http.Response
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var bs []byte
+	if bs, err = io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, string(bs)
+}
+
+// isTestError will return whether or not an error is the provided error, annotated with the authenticator name
+func isTestError(err, target error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), target.Error())
+}
+
+func TestJump_RegisterAuthenticator(t *testing.T) {
+	j := newTestJump(t)
+	if err := j.RegisterAuthenticator(newTestHeaderAuthenticator("custom", "X-Custom", false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.RegisterAuthenticator(newTestHeaderAuthenticator("custom", "X-Custom", false)); !isTestError(err, ErrAuthenticatorExists) {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrAuthenticatorExists, err)
+	}
+
+	if err := j.RegisterAuthenticator(newTestHeaderAuthenticator(AuthenticatorSession, "X-Custom", false)); !isTestError(err, ErrAuthenticatorExists) {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrAuthenticatorExists, err)
+	}
+
+	expected := append(append([]string{}, DefaultAuthChain...), "custom")
+	var chain []string
+	for _, a := range j.getAuthChain(nil) {
+		chain = append(chain, a.Name())
+	}
+
+	if len(chain) != len(expected) {
+		t.Fatalf("invalid chain, expected %v and received %v", expected, chain)
+	}
+
+	for i := range expected {
+		if chain[i] != expected[i] {
+			t.Fatalf("invalid chain, expected %v and received %v", expected, chain)
+		}
+	}
+}
+
+func TestJump_SetAuthChain(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.GET("/whoami", j.NewSetUserIDMW(false, false), func(ctx *httpserve.Context) {
+			ctx.WriteString(200, "text/plain", ctx.Get("authenticator")+":"+ctx.Get("userID"))
+		})
+	})
+
+	for _, a := range []Authenticator{
+		newTestHeaderAuthenticator("first", "X-First", false),
+		newTestHeaderAuthenticator("second", "X-Second", false),
+	} {
+		if err := j.RegisterAuthenticator(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	headers := map[string]string{"X-First": testUser1, "X-Second": testUser2}
+	if _, body := doTestRequest(t, "GET", baseURL+"/whoami", headers); body != "first:"+testUser1 {
+		t.Fatalf("invalid principal, expected <%s> and received <%s>", "first:"+testUser1, body)
+	}
+
+	if err := j.SetAuthChain("second", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, body := doTestRequest(t, "GET", baseURL+"/whoami", headers); body != "second:"+testUser2 {
+		t.Fatalf("invalid principal, expected <%s> and received <%s>", "second:"+testUser2, body)
+	}
+
+	// Authenticators omitted from the chain are disabled
+	if err := j.SetAuthChain("second"); err != nil {
+		t.Fatal(err)
+	}
+
+	if statusCode, _ := doTestRequest(t, "GET", baseURL+"/whoami", map[string]string{"X-First": testUser1}); statusCode != 401 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 401, statusCode)
+	}
+
+	if err := j.SetAuthChain("second", "unknown"); !isTestError(err, ErrAuthenticatorNotFound) {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrAuthenticatorNotFound, err)
+	}
+
+	// The chain is unchanged by a failed update
+	if _, body := doTestRequest(t, "GET", baseURL+"/whoami", headers); body != "second:"+testUser2 {
+		t.Fatalf("invalid principal, expected <%s> and received <%s>", "second:"+testUser2, body)
+	}
+}
+
+func TestJump_NewRestrictedSetUserIDMW(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.GET("/whoami", j.NewRestrictedSetUserIDMW(false, false, "second"), func(ctx *httpserve.Context) {
+			ctx.WriteString(200, "text/plain", ctx.Get("authenticator")+":"+ctx.Get("userID"))
+		})
+	})
+
+	for _, a := range []Authenticator{
+		newTestHeaderAuthenticator("first", "X-First", false),
+		newTestHeaderAuthenticator("second", "X-Second", false),
+	} {
+		if err := j.RegisterAuthenticator(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Credentials for disallowed authenticators are ignored, even when earlier in the chain
+	headers := map[string]string{"X-First": testUser1, "X-Second": testUser2}
+	if _, body := doTestRequest(t, "GET", baseURL+"/whoami", headers); body != "second:"+testUser2 {
+		t.Fatalf("invalid principal, expected <%s> and received <%s>", "second:"+testUser2, body)
+	}
+
+	if statusCode, _ := doTestRequest(t, "GET", baseURL+"/whoami", map[string]string{"X-First": testUser1}); statusCode != 401 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 401, statusCode)
+	}
+}
+
+func TestJump_NewCSRFMW_authenticators(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.POST("/posts", j.NewSetUserIDMW(false, true), j.NewCSRFMW(), func(ctx *httpserve.Context) {
+			ctx.WriteNoContent()
+		})
+	})
+
+	for _, a := range []Authenticator{
+		newTestHeaderAuthenticator("explicit", "X-Explicit", false),
+		newTestHeaderAuthenticator("ambient", "X-Ambient", true),
+	} {
+		if err := j.RegisterAuthenticator(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := j.SetAuthChain(AuthenticatorBasic, "explicit", "ambient"); err != nil {
+		t.Fatal(err)
+	}
+
+	email, password := "basic@example.com", "password1234"
+	if _, _, err := j.CreateUser(email, password); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", baseURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.SetBasicAuth(email, password)
+	basic := req.Header.Get("Authorization")
+
+	type testcase struct {
+		name       string
+		headers    map[string]string
+		statusCode int
+	}
+
+	tcs := []testcase{
+		{name: "unauthenticated", statusCode: 204},
+		{name: "explicit", headers: map[string]string{"X-Explicit": testUser1}, statusCode: 204},
+		{name: "ambient without header", headers: map[string]string{"X-Ambient": testUser1}, statusCode: 403},
+		{name: "ambient with header", headers: map[string]string{"X-Ambient": testUser1, CSRFHeader: "1"}, statusCode: 204},
+		{name: "basic without header", headers: map[string]string{"Authorization": basic}, statusCode: 403},
+		{name: "basic with header", headers: map[string]string{"Authorization": basic, CSRFHeader: "1"}, statusCode: 204},
+	}
+
+	for _, tc := range tcs {
+		if statusCode, _ := doTestRequest(t, "POST", baseURL+"/posts", tc.headers); statusCode != tc.statusCode {
+			t.Fatalf("invalid status code for <%s>, expected <%d> and received <%d>", tc.name, tc.statusCode, statusCode)
+		}
+	}
+}
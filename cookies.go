@@ -0,0 +1,123 @@
+package jump
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gdbu/errors"
+)
+
+const (
+	// ErrInvalidHostPrefixConfig is returned when a __Host- prefixed cookie configuration is not secure, has a domain or a non-root path
+	ErrInvalidHostPrefixConfig = errors.Error("invalid cookie configuration, __Host- prefixed cookies must be secure, have no domain and use a path of \"/\"")
+	// ErrEmptyCookieName is returned when a cookie configuration has an empty key or token name
+	ErrEmptyCookieName = errors.Error("invalid cookie configuration, cookie names cannot be empty")
+)
+
+const hostPrefix = "__Host-"
+
+// DefaultCookieConfig is the cookie configuration used when none has been set
+var DefaultCookieConfig = CookieConfig{
+	KeyName:   CookieKey,
+	TokenName: CookieToken,
+	Path:      "/",
+	HTTPOnly:  true,
+	Secure:    true,
+	SameSite:  http.SameSiteLaxMode,
+}
+
+// CookieConfig represents the attributes applied to session cookies
+type CookieConfig struct {
+	// KeyName is the name of the session key cookie
+	KeyName string `toml:"keyName"`
+	// TokenName is the name of the session token cookie
+	TokenName string `toml:"tokenName"`
+
+	// Domain will allow cookies to be shared across subdomains, when empty cookies are host-only
+	Domain string `toml:"domain"`
+	// Path is the cookie path, defaults to "/"
+	Path string `toml:"path"`
+
+	HTTPOnly bool          `toml:"httpOnly"`
+	Secure   bool          `toml:"secure"`
+	SameSite http.SameSite `toml:"sameSite"`
+
+	// HostPrefix will prefix cookie names with __Host-
+	// Note: This requires Secure to be set, Domain to be empty and Path to be "/"
+	HostPrefix bool `toml:"hostPrefix"`
+}
+
+// Validate will validate a cookie configuration
+func (c *CookieConfig) Validate() (err error) {
+	var errs errors.ErrorList
+	if len(c.KeyName) == 0 || len(c.TokenName) == 0 {
+		errs.Push(ErrEmptyCookieName)
+	}
+
+	if c.HostPrefix && (!c.Secure || len(c.Domain) > 0 || c.getPath() != "/") {
+		errs.Push(ErrInvalidHostPrefixConfig)
+	}
+
+	return errs.Err()
+}
+
+func (c *CookieConfig) keyName() string {
+	return c.getName(c.KeyName)
+}
+
+func (c *CookieConfig) tokenName() string {
+	return c.getName(c.TokenName)
+}
+
+func (c *CookieConfig) getName(name string) string {
+	if !c.HostPrefix {
+		return name
+	}
+
+	return hostPrefix + name
+}
+
+func (c *CookieConfig) getPath() string {
+	if len(c.Path) == 0 {
+		return "/"
+	}
+
+	return c.Path
+}
+
+func (c *CookieConfig) newCookie(name, value string, expires time.Time) (cookie http.Cookie) {
+	cookie.Domain = c.Domain
+	cookie.Name = name
+	cookie.Value = value
+	cookie.Expires = expires
+	cookie.Path = c.getPath()
+	cookie.HttpOnly = c.HTTPOnly
+	cookie.Secure = c.Secure
+	cookie.SameSite = c.SameSite
+	return
+}
+
+func (c *CookieConfig) setCookie(name, value string) (cookie http.Cookie) {
+	return c.newCookie(name, value, time.Now().AddDate(0, 0, 7))
+}
+
+func (c *CookieConfig) unsetCookie(name string) (cookie http.Cookie) {
+	cookie = c.newCookie(name, "", time.Now().AddDate(-1, 0, 0))
+	cookie.MaxAge = -1
+	return
+}
+
+// SetCookieConfig will set the cookie configuration used for session cookies
+func (j *Jump) SetCookieConfig(c CookieConfig) (err error) {
+	if err = c.Validate(); err != nil {
+		return
+	}
+
+	j.cookies = c
+	return
+}
+
+// CookieConfig will return the current cookie configuration
+func (j *Jump) CookieConfig() CookieConfig {
+	return j.cookies
+}
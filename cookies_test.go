@@ -0,0 +1,55 @@
+package jump
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCookieConfig_Validate(t *testing.T) {
+	type testcase struct {
+		name   string
+		modify func(*CookieConfig)
+		err    error
+	}
+
+	tcs := []testcase{
+		{name: "defaults", modify: func(c *CookieConfig) {}},
+		{name: "host prefix", modify: func(c *CookieConfig) { c.HostPrefix = true }},
+		{name: "host prefix with empty path", modify: func(c *CookieConfig) { c.HostPrefix = true; c.Path = "" }},
+		{name: "host prefix without secure", modify: func(c *CookieConfig) { c.HostPrefix = true; c.Secure = false }, err: ErrInvalidHostPrefixConfig},
+		{name: "host prefix with domain", modify: func(c *CookieConfig) { c.HostPrefix = true; c.Domain = "example.com" }, err: ErrInvalidHostPrefixConfig},
+		{name: "host prefix with path", modify: func(c *CookieConfig) { c.HostPrefix = true; c.Path = "/app" }, err: ErrInvalidHostPrefixConfig},
+		{name: "empty key name", modify: func(c *CookieConfig) { c.KeyName = "" }, err: ErrEmptyCookieName},
+		{name: "empty token name", modify: func(c *CookieConfig) { c.TokenName = "" }, err: ErrEmptyCookieName},
+	}
+
+	for _, tc := range tcs {
+		c := DefaultCookieConfig
+		tc.modify(&c)
+		if err := c.Validate(); err != tc.err {
+			t.Fatalf("invalid error for <%s>, expected <%v> and received <%v>", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestCookieConfig_newCookie(t *testing.T) {
+	c := DefaultCookieConfig
+	cookie := c.setCookie(c.keyName(), "value")
+	switch {
+	case cookie.Name != CookieKey:
+		t.Fatalf("invalid name, expected <%s> and received <%s>", CookieKey, cookie.Name)
+	case cookie.Path != "/" || len(cookie.Domain) > 0:
+		t.Fatalf("invalid scope, expected a host-only root path and received <%s> / <%s>", cookie.Domain, cookie.Path)
+	case !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode:
+		t.Fatalf("invalid attributes, received %+v", cookie)
+	}
+
+	c.HostPrefix = true
+	if name := c.tokenName(); name != hostPrefix+CookieToken {
+		t.Fatalf("invalid name, expected <%s> and received <%s>", hostPrefix+CookieToken, name)
+	}
+
+	if cookie = c.unsetCookie(c.keyName()); cookie.MaxAge != -1 || len(cookie.Value) > 0 {
+		t.Fatalf("invalid unset cookie, received %+v", cookie)
+	}
+}
@@ -1,11 +1,28 @@
 package events
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gdbu/errors"
+)
+
+const (
+	// ErrDrainTimeout is returned when queued events could not be drained before the close deadline
+	ErrDrainTimeout = errors.Error("timed out draining queued events")
+)
+
+const (
+	// DefaultDrainTimeout is the amount of time Close will wait for queued events to drain
+	DefaultDrainTimeout = time.Second * 5
+)
 
 func New() *Controller {
 	var c Controller
 	c.s = make(subscribers, 32)
 	c.cond = sync.NewCond(&c.queueMux)
+	c.done = make(chan struct{})
 	go c.scan()
 	return &c
 }
@@ -18,10 +35,21 @@ type Controller struct {
 	queue    []Event
 
 	s subscribers
+
+	// closed is set when the controller is no longer accepting events
+	closed bool
+	// abandoned is set when the drain deadline has passed, remaining events are dropped
+	abandoned bool
+	done      chan struct{}
 }
 
+// New will queue an event to be sent to subscribers
+// Note: Events are dropped once the controller has been closed
 func (c *Controller) New(e Event) {
-	c.appendEvent(e)
+	if !c.appendEvent(e) {
+		return
+	}
+
 	c.cond.Signal()
 }
 
@@ -31,30 +59,78 @@ func (c *Controller) Subscribe(fn func(Event), subscribingTo ...string) {
 	c.s.subscribe(fn, subscribingTo)
 }
 
+// Close will stop accepting events and wait up to DefaultDrainTimeout for queued events to be delivered
+func (c *Controller) Close() (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDrainTimeout)
+	defer cancel()
+	return c.Shutdown(ctx)
+}
+
+// Shutdown will stop accepting events and wait until queued events are delivered or the context is done
+func (c *Controller) Shutdown(ctx context.Context) (err error) {
+	c.setClosed()
+
+	select {
+	case <-c.done:
+		return
+	case <-ctx.Done():
+		c.abandon()
+		return ErrDrainTimeout
+	}
+}
+
 func (c *Controller) scan() {
+	defer close(c.done)
 	for {
-		e := c.getNextEvent()
+		e, ok := c.getNextEvent()
+		if !ok {
+			return
+		}
+
 		c.notify(e)
 	}
 }
 
-func (c *Controller) appendEvent(e Event) {
+func (c *Controller) appendEvent(e Event) (ok bool) {
 	c.queueMux.Lock()
 	defer c.queueMux.Unlock()
+	if c.closed {
+		return false
+	}
+
 	c.queue = append(c.queue, e)
+	return true
 }
 
-func (c *Controller) getNextEvent() (e Event) {
+func (c *Controller) getNextEvent() (e Event, ok bool) {
 	c.queueMux.Lock()
 	defer c.queueMux.Unlock()
-	for len(c.queue) == 0 {
+	for len(c.queue) == 0 && !c.closed {
 		c.cond.Wait()
 	}
 
+	if len(c.queue) == 0 || c.abandoned {
+		return
+	}
+
 	e = c.queue[0]
 	c.queue[0] = Event{}
 	c.queue = c.queue[1:]
-	return e
+	return e, true
+}
+
+func (c *Controller) setClosed() {
+	c.queueMux.Lock()
+	defer c.queueMux.Unlock()
+	c.closed = true
+	c.cond.Broadcast()
+}
+
+func (c *Controller) abandon() {
+	c.queueMux.Lock()
+	defer c.queueMux.Unlock()
+	c.abandoned = true
+	c.queue = nil
 }
 
 func (c *Controller) getFuncs(key string) (out []func(Event)) {
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -56,3 +57,56 @@ func TestControllerAllowsSubscribeDuringNotify(t *testing.T) {
 		t.Fatal("timed out waiting for dynamically subscribed handler")
 	}
 }
+
+func TestControllerCloseDrainsQueue(t *testing.T) {
+	c := New()
+
+	var handled atomic.Int32
+	c.Subscribe(func(e Event) {
+		handled.Add(1)
+	}, "drain")
+
+	for i := range 64 {
+		c.New(MakeEvent("drain", i))
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := handled.Load(); n != 64 {
+		t.Fatalf("invalid handled count, expected %d and received %d", 64, n)
+	}
+
+	// Events published after close are dropped
+	c.New(MakeEvent("drain", nil))
+	if n := handled.Load(); n != 64 {
+		t.Fatalf("invalid handled count, expected %d and received %d", 64, n)
+	}
+}
+
+func TestControllerShutdownDeadline(t *testing.T) {
+	c := New()
+
+	release := make(chan struct{})
+	c.Subscribe(func(e Event) {
+		<-release
+	}, "slow")
+
+	c.New(MakeEvent("slow", nil))
+	c.New(MakeEvent("slow", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	if err := c.Shutdown(ctx); err != ErrDrainTimeout {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrDrainTimeout, err)
+	}
+
+	close(release)
+	select {
+	case <-c.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for scan to exit")
+	}
+}
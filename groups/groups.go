@@ -2,6 +2,7 @@ package groups
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gdbu/stringset"
 	"github.com/mojura/mojura"
@@ -34,6 +35,9 @@ func New(opts mojura.Opts) (gp *Groups, err error) {
 // Groups manages the users
 type Groups struct {
 	c *mojura.Mojura[*Entry]
+
+	mux      sync.RWMutex
+	handlers []func(userID string)
 }
 
 // Get will get an Entry by user ID
@@ -94,6 +98,11 @@ func (g *Groups) AddGroups(userID string, groups ...string) (updated *Entry, err
 		return
 	})
 
+	if err != nil {
+		return
+	}
+
+	g.notify(userID)
 	return
 }
 
@@ -118,9 +127,21 @@ func (g *Groups) RemoveGroups(userID string, groups ...string) (updated *Entry,
 		return
 	})
 
+	if err != nil {
+		return
+	}
+
+	g.notify(userID)
 	return
 }
 
+// OnChange will register a handler which is called with the user ID after the groups of a user change
+func (g *Groups) OnChange(fn func(userID string)) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	g.handlers = append(g.handlers, fn)
+}
+
 // HasGroup will determine if a user ID has a given group
 func (g *Groups) HasGroup(userID string, group string) (hasGroup bool, err error) {
 	err = g.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Entry]) (err error) {
@@ -155,6 +176,15 @@ func (g *Groups) Close() (err error) {
 	return g.c.Close()
 }
 
+// notify will call the registered change handlers with a given user ID
+func (g *Groups) notify(userID string) {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+	for _, fn := range g.handlers {
+		fn(userID)
+	}
+}
+
 // new will create an Entry for a given user ID
 func (g *Groups) new(txn *mojura.Transaction[*Entry], userID string, groups []string) (created *Entry, err error) {
 	var e Entry
@@ -2,9 +2,13 @@ package jump
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
 	"github.com/mojura/mojura"
+	"github.com/vroomy/httpserve"
 
 	"github.com/gdbu/errors"
 
@@ -12,7 +16,10 @@ import (
 	"github.com/gdbu/jump/events"
 	"github.com/gdbu/jump/groups"
 	"github.com/gdbu/jump/permissions"
+	"github.com/gdbu/jump/ratelimit"
+	"github.com/gdbu/jump/serviceaccounts"
 	"github.com/gdbu/jump/sessions"
+	"github.com/gdbu/jump/signing"
 	"github.com/gdbu/jump/sso"
 	"github.com/gdbu/jump/users"
 )
@@ -22,6 +29,44 @@ const (
 	ErrUserIDIsEmpty = errors.Error("userID is empty")
 	// ErrResourceIDIsEmpty is returned when resource id is expected but not found withing a permissions hook
 	ErrResourceIDIsEmpty = errors.Error("resourceID is empty")
+	// ErrInvalidCSRFToken is returned when a state-changing request has a missing or non-matching CSRF token
+	ErrInvalidCSRFToken = errors.Error("invalid CSRF token")
+	// ErrReauthenticationRequired is returned when a session's most recent authentication is too old
+	ErrReauthenticationRequired = errors.Error("reauthentication required")
+	// ErrInsufficientAssurance is returned when a session was authenticated at a lower assurance level than required
+	ErrInsufficientAssurance = errors.Error("insufficient authentication assurance level")
+	// ErrRateLimitExceeded is returned when a principal has exceeded its request rate limit
+	ErrRateLimitExceeded = errors.Error("rate limit exceeded")
+)
+
+const (
+	// AuthMethodAPIKey is the context authMethod value for requests authenticated by API key
+	AuthMethodAPIKey = "apiKey"
+	// AuthMethodSession is the context authMethod value for requests authenticated by session cookies
+	AuthMethodSession = "session"
+	// AuthMethodBearer is the context authMethod value for requests authenticated by a session bearer token
+	AuthMethodBearer = "bearer"
+	// AuthMethodSigned is the context authMethod value for requests authenticated by an API key signature
+	AuthMethodSigned = "signed"
+	// AuthMethodBasic is the context authMethod value for requests authenticated by HTTP Basic credentials
+	AuthMethodBasic = "basic"
+)
+
+const (
+	// CSRFHeader is the request header expected to contain the CSRF token
+	CSRFHeader = "X-CSRF-Token"
+	// AssuranceHeader is the response header containing the assurance level required to proceed
+	AssuranceHeader = "X-Required-Assurance"
+	// BearerHeader is the response header containing a re-issued bearer token after a session rotation
+	// Note: Bearer clients must check every response for this header and replace their stored token with
+	// its value, the previous token is invalidated. The header is listed within Access-Control-Expose-Headers
+	// so it is readable by cross-origin clients
+	BearerHeader = "X-Session-Bearer"
+)
+
+const (
+	// EventAPIKeyIPRejected is emitted when an api key is presented from an IP outside of its allowlist
+	EventAPIKeyIPRejected = "api-key-ip-rejected"
 )
 
 const (
@@ -29,28 +74,31 @@ const (
 )
 
 const (
-	// CookieKey is the jump HTTP key
+	// CookieKey is the default jump HTTP key cookie name
 	CookieKey = "jump_key"
-	// CookieToken is the jump HTTP token
+	// CookieToken is the default jump HTTP token cookie name
 	CookieToken = "jump_token"
 )
 
 // New will return a new instance of Jump
+// Note: Custom actions (see permissions.RegisterAction) must be registered before calling New, stored
+// permissions are read during initialization and unregistered action names are skipped
 func New(opts mojura.Opts) (jp *Jump, err error) {
 	var j Jump
 	j.out = mojura.NewLogger()
+	j.cookies = DefaultCookieConfig
 	j.evts = events.New()
 	if j.perm, err = permissions.New(opts); err != nil {
 		err = fmt.Errorf("error initializing permissions: %v", err)
 		return
 	}
 
-	if j.sess, err = sessions.New(opts); err != nil {
+	if j.sess, err = sessions.New(opts, j.evts); err != nil {
 		err = fmt.Errorf("error initializing sessions: %v", err)
 		return
 	}
 
-	if j.api, err = apikeys.New(opts); err != nil {
+	if j.api, err = apikeys.New(opts, j.evts); err != nil {
 		err = fmt.Errorf("error initializing API keys: %v", err)
 		return
 	}
@@ -60,6 +108,11 @@ func New(opts mojura.Opts) (jp *Jump, err error) {
 		return
 	}
 
+	if j.svcs, err = serviceaccounts.New(opts, j.evts); err != nil {
+		err = fmt.Errorf("error initializing service accounts: %v", err)
+		return
+	}
+
 	if j.grps, err = groups.New(opts); err != nil {
 		err = fmt.Errorf("error initializing groups: %v", err)
 		return
@@ -71,6 +124,13 @@ func New(opts mojura.Opts) (jp *Jump, err error) {
 	}
 
 	j.perm.SetGroups(j.grps)
+	j.sessionLimits = make(map[string]sessions.Limit)
+	j.sess.SetLimitFunc(j.getSessionLimit)
+	j.verifier = signing.NewVerifier(signing.DefaultMaxSkew)
+	j.registerDefaultAuthenticators()
+	j.limiter = ratelimit.NewMemory()
+	j.keyRateLimits = make(map[string]ratelimit.Limit)
+	j.groupRateLimits = make(map[string]ratelimit.Limit)
 	jp = &j
 	return
 }
@@ -79,54 +139,147 @@ func New(opts mojura.Opts) (jp *Jump, err error) {
 type Jump struct {
 	out mojura.Logger
 
+	cookies CookieConfig
+
+	limitMux            sync.RWMutex
+	sessionLimits       map[string]sessions.Limit
+	defaultSessionLimit sessions.Limit
+
+	rateMux         sync.RWMutex
+	limiter         ratelimit.Store
+	keyRateLimits   map[string]ratelimit.Limit
+	groupRateLimits map[string]ratelimit.Limit
+
+	verifier *signing.Verifier
+
+	authMux        sync.RWMutex
+	authenticators map[string]Authenticator
+	authChain      []string
+
+	proxyMux       sync.RWMutex
+	trustedProxies []*net.IPNet
+
+	permDebug atomic.Uint32
+
 	perm *permissions.Permissions
 	sess *sessions.Sessions
 	api  *apikeys.APIKeys
 	usrs *users.Users
+	svcs *serviceaccounts.ServiceAccounts
 	grps *groups.Groups
 	sso  *sso.Controller
 	evts *events.Controller
 }
 
-func (j *Jump) getUserIDFromAPIKey(apiKey string) (userID string, err error) {
+// getUserIDFromAPIKey will return the principal ID and key ID for an api key and record its usage from the provided IP
+// Note: The principal ID is either a user ID or a service account principal ID
+func (j *Jump) getUserIDFromAPIKey(apiKey, ip string) (userID, keyID string, err error) {
 	var a *apikeys.APIKey
 	if a, err = j.api.Get(apiKey); err != nil {
 		err = fmt.Errorf("error getting api key information: %v", err)
 		return
 	}
 
-	var u *users.User
-	if u, err = j.usrs.Get(a.UserID); err != nil {
-		err = fmt.Errorf("error getting user \"%s\": %v", a.UserID, err)
+	return j.authorizeAPIKey(a, ip)
+}
+
+// getUserIDFromSignedRequest will verify the signature of a request and return the principal ID and key ID which signed it
+func (j *Jump) getUserIDFromSignedRequest(req *http.Request, ip string) (userID, keyID string, err error) {
+	var a *apikeys.APIKey
+	if _, err = j.verifier.Verify(req, func(keyID string) (key []byte, err error) {
+		if a, err = j.api.GetForSigning(keyID); err != nil {
+			return
+		}
+
+		key = a.SigningKey()
+		return
+	}); err != nil {
+		err = fmt.Errorf("error verifying request signature: %v", err)
 		return
 	}
 
-	if u.Disabled {
-		err = users.ErrUserIsDisabled
+	return j.authorizeAPIKey(a, ip)
+}
+
+// authorizeAPIKey will ensure the principal of an api key is enabled and the key is allowed from the provided IP
+func (j *Jump) authorizeAPIKey(a *apikeys.APIKey, ip string) (userID, keyID string, err error) {
+	if err = j.assertPrincipalEnabled(a.UserID); err != nil {
+		return
+	}
+
+	if !a.IsAllowedIP(ip) {
+		j.out.Warn(fmt.Sprintf("rejected api key %s for user %s from IP %s, outside of allowed CIDRs", a.SanitizedID(), a.UserID, ip))
+		j.evts.New(events.MakeEvent(EventAPIKeyIPRejected, makeIPRejection(a, ip)))
+		err = apikeys.ErrIPNotAllowed
 		return
 	}
 
+	j.api.RecordUsage(a.Key, ip)
 	userID = a.UserID
+	keyID = a.Key
 	return
 }
 
-func (j *Jump) getUserIDFromSession(req *http.Request) (userID string, err error) {
-	var key *http.Cookie
-	if key, err = req.Cookie(CookieKey); err != nil {
+// assertPrincipalEnabled will ensure the user or service account exists and is not disabled
+func (j *Jump) assertPrincipalEnabled(principalID string) (err error) {
+	if serviceaccounts.IsPrincipalID(principalID) {
+		var sa *serviceaccounts.ServiceAccount
+		if sa, err = j.svcs.Get(principalID); err != nil {
+			return fmt.Errorf("error getting service account \"%s\": %v", principalID, err)
+		}
+
+		if sa.Disabled {
+			return serviceaccounts.ErrServiceAccountIsDisabled
+		}
+
 		return
 	}
 
-	var token *http.Cookie
-	if token, err = req.Cookie(CookieToken); err != nil {
+	var u *users.User
+	if u, err = j.usrs.Get(principalID); err != nil {
+		return fmt.Errorf("error getting user \"%s\": %v", principalID, err)
+	}
+
+	if u.Disabled {
+		return users.ErrUserIsDisabled
+	}
+
+	return
+}
+
+// isAllowedByAPIKey will return whether or not the API key (if any) used to authenticate the request permits an action on a resource
+func (j *Jump) isAllowedByAPIKey(ctx *httpserve.Context, resourceKey string, action permissions.Action) (ok bool, err error) {
+	var keyID string
+	if keyID = ctx.Get("apiKeyID"); len(keyID) == 0 {
+		return true, nil
+	}
+
+	var a *apikeys.APIKey
+	if a, err = j.api.GetByID(keyID); err != nil {
 		return
 	}
 
+	ok = a.Allows(resourceKey, action)
+	return
+}
+
+// getUserIDFromSession will return the user ID of a session key/token pair delivered using the provided auth method
+// Note: The ID of the resolved session is stored within the context, see getContextSession
+func (j *Jump) getUserIDFromSession(ctx *httpserve.Context, method, key, token string) (userID string, err error) {
 	var sess *sessions.Session
-	if sess, err = j.sess.Get(key.Value, token.Value); err != nil {
+	if sess, err = j.sess.Get(key, token); err != nil {
 		return
 	}
 
 	userID = sess.UserID
+	if sess.RotationRequired {
+		// Privileges have changed since this session was issued, re-issue the session ID
+		// Note: The replacement session is stored within the context by rotateSession
+		err = j.rotateSession(ctx, method, key, token)
+		return
+	}
+
+	ctx.Put("sessionID", sess.ID)
 	return
 }
 
@@ -166,11 +319,16 @@ func (j *Jump) SSO() *sso.Controller {
 }
 
 // Close will close jump
+// Note: Queued events are drained before the underlying stores are closed
 func (j *Jump) Close() (err error) {
 	var errs errors.ErrorList
-	errs.Push(j.usrs.Close())
+	errs.Push(j.evts.Close())
 	errs.Push(j.sess.Close())
+	errs.Push(j.sso.Close())
+	errs.Push(j.usrs.Close())
+	errs.Push(j.svcs.Close())
 	errs.Push(j.api.Close())
+	errs.Push(j.grps.Close())
 	errs.Push(j.perm.Close())
 	return errs.Err()
 }
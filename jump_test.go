@@ -0,0 +1,84 @@
+package jump
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mojura/mojura"
+	"github.com/vroomy/httpserve"
+)
+
+const (
+	testUser1 = "TEST_USER_1"
+	testUser2 = "TEST_USER_2"
+)
+
+func newTestJump(t *testing.T) (j *Jump) {
+	var (
+		opts mojura.Opts
+		err  error
+	)
+
+	opts.Dir = t.TempDir()
+	if j, err = New(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := j.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return
+}
+
+// newTestServer will serve the routes registered by the provided func and return the base URL
+func newTestServer(t *testing.T, register func(s *httpserve.Serve) error) (baseURL string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := l.Addr().(*net.TCPAddr).Port
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := httpserve.New()
+	if err = register(s); err != nil {
+		t.Fatal(err)
+	}
+
+	go s.Listen(uint16(port))
+	t.Cleanup(func() { s.Close() })
+
+	baseURL = fmt.Sprintf("http://127.0.0.1:%d", port)
+	for i := 0; i < 100; i++ {
+		var conn net.Conn
+		if conn, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			conn.Close()
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("error waiting for test server: %v", err)
+	return
+}
+
+// getResponseCookie will return the value of a cookie set by a response
+// Note: When the cookie is set multiple times (e.g. the session was rotated twice), the last value applies
+func getResponseCookie(res *http.Response, name string) (value string) {
+	for _, c := range res.Cookies() {
+		if c.Name == name {
+			value = c.Value
+		}
+	}
+
+	return
+}
@@ -3,9 +3,11 @@ package jump
 import (
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/gdbu/errors"
 	"github.com/gdbu/jump/permissions"
+	"github.com/gdbu/jump/sessions"
 
 	"github.com/vroomy/httpserve"
 )
@@ -33,14 +35,23 @@ func (j *Jump) NewGrantPermissionsMW(resourceName string, actions, adminActions
 }
 
 // NewSetUserIDMW will set the user id of the currently logged in user
+// Note: The request is authenticated by the authenticator chain, see SetAuthChain
 func (j *Jump) NewSetUserIDMW(redirectOnFail, allowNonLoggedIn bool) (fn httpserve.Handler) {
+	return j.NewRestrictedSetUserIDMW(redirectOnFail, allowNonLoggedIn)
+}
+
+// NewRestrictedSetUserIDMW will set the user id of the currently logged in user, only
+// accepting the provided authenticators (by name). Credentials for other authenticators are ignored.
+// When no authenticators are provided, the full authenticator chain is accepted.
+// Note: e.g. AuthenticatorSession and AuthenticatorBearer for browser routes, forbidding query string API keys
+func (j *Jump) NewRestrictedSetUserIDMW(redirectOnFail, allowNonLoggedIn bool, authenticators ...string) (fn httpserve.Handler) {
 	return func(ctx *httpserve.Context) {
 		var (
 			userID string
 			err    error
 		)
 
-		userID, err = j.getUserIDFromRequest(ctx)
+		userID, err = j.authenticate(ctx, authenticators)
 		switch {
 		case err == nil:
 			// No error occurred, set user ID
@@ -58,6 +69,8 @@ func (j *Jump) NewSetUserIDMW(redirectOnFail, allowNonLoggedIn bool) (fn httpser
 }
 
 // NewCheckPermissionsMW will check the user to ensure they have permissions to view a particular resource
+// Note: See SetPermissionsDebug to log or respond with the explanation of denied requests
+// Note: Requests authenticated by a scoped API key are additionally restricted to the key's scope
 func (j *Jump) NewCheckPermissionsMW(resourceName, paramKey string) httpserve.Handler {
 	return func(ctx *httpserve.Context) {
 		userID := ctx.Get("userID")
@@ -88,27 +101,80 @@ func (j *Jump) NewCheckPermissionsMW(resourceName, paramKey string) httpserve.Ha
 			resourceID = resourceName
 		}
 
-		if !j.perm.Can(userID, resourceID, action) {
-			ctx.WriteJSON(403, errors.Error("forbidden"))
+		if !j.can(ctx, userID, resourceID, action) {
 			return
 		}
+
+		// Intersect the user's permissions with the scope of the API key used (if any)
+		allowed, err := j.isAllowedByAPIKey(ctx, resourceID, action)
+		switch {
+		case err != nil:
+			ctx.WriteJSON(500, fmt.Errorf("error checking api key scope: %v", err))
+		case !allowed:
+			ctx.WriteJSON(403, errors.Error("forbidden, outside of api key scope"))
+		}
 	}
 }
 
-func (j *Jump) getUserIDFromRequest(ctx *httpserve.Context) (userID string, err error) {
-	if apiKey := getAPIKey(ctx); len(apiKey) > 0 {
-		if userID, err = j.getUserIDFromAPIKey(apiKey); err != nil {
-			err = fmt.Errorf("error getting user ID from API key: %v", err)
+// NewCSRFMW will validate the CSRF token of state-changing requests authenticated by ambient credentials
+// Note: This should be placed after NewSetUserIDMW. Only requests authenticated by an AmbientAuthenticator (session
+// cookies and HTTP Basic by default) are checked, API keys, signatures and bearer tokens are exempt.
+// Session requests must provide the session CSRF token within the X-CSRF-Token header, see GetCSRFToken for retrieval.
+// Other ambient requests have no session token to match, they must provide a non-empty X-CSRF-Token header, which
+// cross-site forms cannot set and cross-origin scripts cannot send without passing a CORS preflight.
+func (j *Jump) NewCSRFMW() httpserve.Handler {
+	return func(ctx *httpserve.Context) {
+		switch ctx.Request().Method {
+		case "GET", "HEAD", "OPTIONS":
+			return
+		}
+
+		if !j.isAmbientAuthenticator(ctx.Get("authenticator")) {
+			// Credentials are not sent ambiently by browsers (or the request is not authenticated), no CSRF check is needed
 			return
 		}
 
-		return
-	}
+		csrfToken := ctx.Request().Header.Get(CSRFHeader)
+		if len(ctx.Get("sessionID")) == 0 {
+			if len(csrfToken) == 0 {
+				ctx.WriteJSON(403, ErrInvalidCSRFToken)
+			}
+
+			return
+		}
+
+		// The session resolved by the authenticator chain is used, the request cookies
+		// are no longer valid when the session was rotated while authenticating
+		sess, err := j.getContextSession(ctx)
+		if err != nil {
+			ctx.WriteJSON(403, ErrInvalidCSRFToken)
+			return
+		}
 
-	if userID, err = j.getUserIDFromSession(ctx.Request()); err != nil {
-		err = fmt.Errorf("error getting user ID from session key: %v", err)
-		return
+		if !sess.IsCSRFMatch(csrfToken) {
+			ctx.WriteJSON(403, ErrInvalidCSRFToken)
+			return
+		}
 	}
+}
 
-	return
+// NewRequireFreshAuthMW will ensure the session presented by the request was authenticated within
+// the provided max age at the provided assurance level (or higher)
+// Note: This should be placed after NewSetUserIDMW. Requests which are not authenticated by a session
+// (e.g. API key) are rejected. See Reauthenticate.
+func (j *Jump) NewRequireFreshAuthMW(maxAge time.Duration, level sessions.AssuranceLevel) httpserve.Handler {
+	return func(ctx *httpserve.Context) {
+		// The session resolved by the authenticator chain is used, see NewCSRFMW
+		sess, err := j.getContextSession(ctx)
+		switch {
+		case err != nil:
+			ctx.WriteJSON(401, ErrReauthenticationRequired)
+		case sess.AssuranceLevel < level:
+			ctx.Writer().Header().Set(AssuranceHeader, level.String())
+			ctx.WriteJSON(401, ErrInsufficientAssurance)
+		case !sess.IsFresh(maxAge, level):
+			ctx.Writer().Header().Set(AssuranceHeader, level.String())
+			ctx.WriteJSON(401, ErrReauthenticationRequired)
+		}
+	}
 }
@@ -0,0 +1,166 @@
+package jump
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/sessions"
+)
+
+func TestJump_NewCSRFMW_rotation(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.POST("/posts", j.NewSetUserIDMW(false, false), j.NewCSRFMW(), func(ctx *httpserve.Context) {
+			ctx.WriteNoContent()
+		})
+	})
+
+	key, token, err := j.sess.New(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var csrfToken string
+	if csrfToken, err = j.sess.GetCSRFToken(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(key, token, csrfToken string) (res *http.Response) {
+		req, err := http.NewRequest("POST", baseURL+"/posts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.AddCookie(&http.Cookie{Name: CookieKey, Value: key})
+		req.AddCookie(&http.Cookie{Name: CookieToken, Value: token})
+		req.Header.Set(CSRFHeader, csrfToken)
+		if res, err = http.DefaultClient.Do(req); err != nil {
+			t.Fatal(err)
+		}
+
+		res.Body.Close()
+		return
+	}
+
+	if res := post(key, token, "invalid"); res.StatusCode != 403 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 403, res.StatusCode)
+	}
+
+	// Privilege changes rotate the session on its next use
+	if err = j.sess.MarkForRotation(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	res := post(key, token, csrfToken)
+	if res.StatusCode != 204 {
+		t.Fatalf("invalid status code for the rotating request, expected <%d> and received <%d>", 204, res.StatusCode)
+	}
+
+	newKey, newToken := getResponseCookie(res, CookieKey), getResponseCookie(res, CookieToken)
+	if len(newKey) == 0 || newKey == key {
+		t.Fatal("expected the session to be rotated")
+	}
+
+	if res = post(newKey, newToken, csrfToken); res.StatusCode != 204 {
+		t.Fatalf("invalid status code for the rotated session, expected <%d> and received <%d>", 204, res.StatusCode)
+	}
+
+	if res = post(key, token, csrfToken); res.StatusCode != 401 {
+		t.Fatalf("invalid status code for the previous session, expected <%d> and received <%d>", 401, res.StatusCode)
+	}
+}
+
+func TestJump_NewRequireFreshAuthMW_rotation(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.GET("/settings", j.NewSetUserIDMW(false, false), j.NewRequireFreshAuthMW(time.Minute, sessions.AssuranceSingleFactor), func(ctx *httpserve.Context) {
+			ctx.WriteNoContent()
+		})
+	})
+
+	key, token, err := j.sess.New(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Privilege changes rotate the session on its next use
+	if err = j.sess.MarkForRotation(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequest("GET", baseURL+"/settings", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+sessions.FormatBearer(key, token))
+
+	var res *http.Response
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != 204 {
+		t.Fatalf("invalid status code for the rotating request, expected <%d> and received <%d>", 204, res.StatusCode)
+	}
+
+	if len(res.Header.Get(BearerHeader)) == 0 {
+		t.Fatal("expected the bearer session to be rotated")
+	}
+
+	if exposed := res.Header.Get("Access-Control-Expose-Headers"); exposed != BearerHeader {
+		t.Fatalf("invalid exposed headers, expected <%s> and received <%s>", BearerHeader, exposed)
+	}
+}
+
+func TestJump_NewCheckPermissionsMW_debug(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.GET("/posts", j.NewSetUserIDMW(false, false), j.NewCheckPermissionsMW("posts", ""), func(ctx *httpserve.Context) {
+			ctx.WriteNoContent()
+		})
+	})
+
+	if err := j.RegisterAuthenticator(newTestHeaderAuthenticator("test", "X-Test", false)); err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{"X-Test": testUser1}
+	for _, mode := range []PermissionsDebugMode{PermissionsDebugOff, PermissionsDebugLog} {
+		j.SetPermissionsDebug(mode)
+		statusCode, body := doTestRequest(t, "GET", baseURL+"/posts", headers)
+		if statusCode != 403 {
+			t.Fatalf("invalid status code for mode %d, expected <%d> and received <%d>", mode, 403, statusCode)
+		}
+
+		if strings.Contains(body, "decision") {
+			t.Fatalf("expected the explanation to be omitted for mode %d and received <%s>", mode, body)
+		}
+	}
+
+	j.SetPermissionsDebug(PermissionsDebugRespond)
+	statusCode, body := doTestRequest(t, "GET", baseURL+"/posts", headers)
+	if statusCode != 403 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 403, statusCode)
+	}
+
+	var f forbiddenExplanation
+	if err := json.Unmarshal([]byte(body), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.Errors) != 1 || f.Errors[0] != "forbidden" {
+		t.Fatalf("invalid errors, expected <%v> and received <%v>", []string{"forbidden"}, f.Errors)
+	}
+
+	d := f.Decision
+	if d.Allowed || d.UserID != testUser1 || d.ResourceKey != "posts" || len(d.Reason) == 0 {
+		t.Fatalf("invalid decision, received %+v", d)
+	}
+}
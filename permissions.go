@@ -1,6 +1,34 @@
 package jump
 
-import "github.com/gdbu/jump/permissions"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gdbu/errors"
+	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/permissions"
+)
+
+const (
+	// PermissionsDebugOff will respond to denied requests without an explanation
+	PermissionsDebugOff PermissionsDebugMode = iota
+	// PermissionsDebugLog will log the explanation of denied requests
+	PermissionsDebugLog
+	// PermissionsDebugRespond will log the explanation of denied requests and include it within the response
+	// Note: Explanations expose groups and grants, this should not be used in production
+	PermissionsDebugRespond
+)
+
+// PermissionsDebugMode controls how NewCheckPermissionsMW reports denied requests
+type PermissionsDebugMode uint32
+
+// forbiddenExplanation is the response body of a denied request when responding with explanations
+// Note: Errors matches the httpserve error response, clients can handle both bodies alike
+type forbiddenExplanation struct {
+	Errors   []string             `json:"errors"`
+	Decision permissions.Decision `json:"decision"`
+}
 
 // SetPermission will give permissions to a provided group for a resourceKey
 // Note: See NewResourceKey for more context
@@ -23,14 +51,105 @@ func (j *Jump) UnsetPermission(resourceKey, group string) (err error) {
 	return j.perm.UnsetPermissions(resourceKey, group)
 }
 
+// SetDenyPermission will deny actions to a provided group for a resourceKey, denies take priority over grants
+// Note: See NewResourceKey for more context
+func (j *Jump) SetDenyPermission(resourceKey, group string, actions permissions.Action) (err error) {
+	return j.perm.SetDenyPermissions(resourceKey, group, actions)
+}
+
+// UnsetDenyPermission will remove the actions denied to a provided group for a resourceKey
+func (j *Jump) UnsetDenyPermission(resourceKey, group string) (err error) {
+	return j.perm.UnsetDenyPermissions(resourceKey, group)
+}
+
 // AddToGroup will add a user to a group
+// Note: The user's sessions will be rotated on their next use
 func (j *Jump) AddToGroup(userID, group string) (err error) {
-	_, err = j.grps.AddGroups(userID, group)
-	return
+	if _, err = j.grps.AddGroups(userID, group); err != nil {
+		return
+	}
+
+	return j.sess.MarkForRotation(userID)
 }
 
 // RemoveFromGroup will remove a user from a group
+// Note: The user's sessions will be rotated on their next use
 func (j *Jump) RemoveFromGroup(userID, group string) (err error) {
-	_, err = j.grps.RemoveGroups(userID, group)
+	if _, err = j.grps.RemoveGroups(userID, group); err != nil {
+		return
+	}
+
+	return j.sess.MarkForRotation(userID)
+}
+
+// SetRole will create or redefine a role, redefining a role changes access everywhere it is assigned
+func (j *Jump) SetRole(name string, actions permissions.Action) (err error) {
+	return j.perm.SetRole(name, actions)
+}
+
+// SetRolePermission will assign a role to a provided group for a resourceKey
+// Note: See NewResourceKey for more context
+func (j *Jump) SetRolePermission(resourceKey, group, role string) (err error) {
+	return j.perm.SetRolePermissions(resourceKey, group, role)
+}
+
+// UnsetRolePermission will remove the role assigned to a provided group for a resourceKey
+func (j *Jump) UnsetRolePermission(resourceKey, group string) (err error) {
+	return j.perm.UnsetRolePermissions(resourceKey, group)
+}
+
+// ExplainPermission will return the decision of whether a user can perform an action on a resourceKey
+func (j *Jump) ExplainPermission(userID, resourceKey string, action permissions.Action) (d permissions.Decision) {
+	return j.perm.Explain(userID, resourceKey, action)
+}
+
+// ListAccessibleResources will return the IDs of the resources (by resource name) a user can perform an action on
+// Note: Results are paginated, see permissions.Permissions.ListAccessible for more context
+func (j *Jump) ListAccessibleResources(userID, resourceName string, action permissions.Action, cursor string) (resourceIDs []string, next string, err error) {
+	return j.perm.ListAccessible(userID, resourceName, action, cursor)
+}
+
+// ListResourceGrantees will return the grants held directly on a resourceKey
+// Note: See NewResourceKey for more context
+func (j *Jump) ListResourceGrantees(resourceKey string) (grants []permissions.Grant, err error) {
+	return j.perm.ListGrantees(resourceKey)
+}
+
+// SetPermissionsDebug will set how NewCheckPermissionsMW reports denied requests
+func (j *Jump) SetPermissionsDebug(mode PermissionsDebugMode) {
+	j.permDebug.Store(uint32(mode))
+}
+
+// can will check the permissions of a user and write the forbidden response when denied
+func (j *Jump) can(ctx *httpserve.Context, userID, resourceKey string, action permissions.Action) (ok bool) {
+	mode := PermissionsDebugMode(j.permDebug.Load())
+	if mode == PermissionsDebugOff {
+		if ok = j.perm.Can(userID, resourceKey, action); !ok {
+			ctx.WriteJSON(403, errors.Error("forbidden"))
+		}
+
+		return
+	}
+
+	d := j.perm.Explain(userID, resourceKey, action)
+	if d.Allowed {
+		return true
+	}
+
+	bs, _ := json.Marshal(d)
+	j.out.Warn(fmt.Sprintf("permission denied for %s %s: %s", ctx.Request().Method, ctx.Request().URL.Path, bs))
+	if mode == PermissionsDebugRespond {
+		// httpserve only accepts errors as the value of an error response, the explanation is encoded directly
+		bs, _ = json.Marshal(forbiddenExplanation{Errors: []string{"forbidden"}, Decision: d})
+		ctx.WriteBytes(403, "application/json", bs)
+		return
+	}
+
+	ctx.WriteJSON(403, errors.Error("forbidden"))
 	return
 }
+
+// PermissionsCacheStats will return the metrics of the authorization caches
+func (j *Jump) PermissionsCacheStats() (stats permissions.CacheMetrics) {
+	return j.perm.CacheStats()
+}
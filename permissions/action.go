@@ -1,12 +1,34 @@
 package permissions
 
-// Action represents an action type
-type Action uint8
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
 
-// Can will return if an action can peform an action request
-func (a Action) Can(ar Action) (can bool) {
-	return a&ar != 0
-}
+	"github.com/gdbu/errors"
+)
+
+const (
+	// ErrActionExists is returned when registering an action name which is already registered
+	ErrActionExists = errors.Error("action with this name already exists")
+	// ErrActionLimitReached is returned when all action bits have been registered
+	ErrActionLimitReached = errors.Error("action limit reached, all action bits are registered")
+	// ErrUnknownAction is returned when parsing an action name which has not been registered
+	ErrUnknownAction = errors.Error("unknown action")
+	// ErrInvalidActionName is returned when registering an action with an invalid name
+	ErrInvalidActionName = errors.Error("invalid action name, cannot be empty or contain separators")
+)
+
+// actionSeparator separates action names within a combined action string (e.g. read|write)
+const actionSeparator = "|"
+
+// Action represents a set of actions as a bitmask
+type Action uint64
 
 const (
 	// ActionNone represents a zero value, no action
@@ -18,3 +40,210 @@ const (
 	// ActionDelete represents a deleting action
 	ActionDelete
 )
+
+var registry = newActionRegistry()
+
+// RegisterAction will register a custom action by name and return its bit
+// Note: Actions should be registered during initialization, in a consistent order, before any permissions are read
+func RegisterAction(name string) (a Action, err error) {
+	return registry.register(name)
+}
+
+// ParseAction will parse a combined action string (e.g. read|write) into an Action
+// Note: Numeric segments are accepted for actions without registered names
+func ParseAction(str string) (a Action, err error) {
+	return parseAction(str, true)
+}
+
+// parseAction will parse a combined action string, unregistered names are only rejected when strict
+func parseAction(str string, strict bool) (a Action, err error) {
+	for _, name := range strings.Split(str, actionSeparator) {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+
+		if n, parseErr := strconv.ParseUint(name, 10, 64); parseErr == nil {
+			a |= Action(n)
+			continue
+		}
+
+		match, ok := registry.get(name)
+		switch {
+		case ok:
+		case strict:
+			return 0, fmt.Errorf("%v: <%s>", ErrUnknownAction, name)
+		default:
+			log.Printf("Permissions: skipping unknown action <%s>, actions must be registered before permissions are read", name)
+			continue
+		}
+
+		a |= match
+	}
+
+	return
+}
+
+// ActionNames will return the names of all registered actions, in bit order
+func ActionNames() (names []string) {
+	return registry.names()
+}
+
+// Can will return if an action can peform an action request
+// Note: This has any semantics, see HasAll for checking combined actions
+func (a Action) Can(ar Action) (can bool) {
+	return a.HasAny(ar)
+}
+
+// HasAll will return if every action within the provided actions is included
+func (a Action) HasAll(actions Action) bool {
+	return actions != 0 && a&actions == actions
+}
+
+// HasAny will return if at least one action within the provided actions is included
+func (a Action) HasAny(actions Action) bool {
+	return a&actions != 0
+}
+
+// Names will return the names of the included actions
+// Note: Bits without a registered name are represented numerically
+func (a Action) Names() (names []string) {
+	for remaining := a; remaining != 0; {
+		bit := Action(1) << bits.TrailingZeros64(uint64(remaining))
+		remaining &^= bit
+		if name, ok := registry.name(bit); ok {
+			names = append(names, name)
+			continue
+		}
+
+		names = append(names, strconv.FormatUint(uint64(bit), 10))
+	}
+
+	return
+}
+
+// String will return the combined action string (e.g. read|write)
+func (a Action) String() string {
+	return strings.Join(a.Names(), actionSeparator)
+}
+
+// MarshalText will marshal the action as a combined action string
+func (a Action) MarshalText() (text []byte, err error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText will unmarshal a combined action string
+// Note: Unregistered names are skipped with a logged warning so stored resources can always be read,
+// use ParseAction to validate input
+func (a *Action) UnmarshalText(text []byte) (err error) {
+	*a, err = parseAction(string(text), false)
+	return
+}
+
+// UnmarshalJSON will unmarshal an action from a combined action string, a list of names, or a bitmask number
+// Note: Bitmask numbers are accepted for data stored prior to action names
+func (a *Action) UnmarshalJSON(bs []byte) (err error) {
+	bs = bytes.TrimSpace(bs)
+	switch {
+	case len(bs) == 0 || bytes.Equal(bs, []byte("null")):
+		return
+	case bs[0] == '[':
+		var names []string
+		if err = json.Unmarshal(bs, &names); err != nil {
+			return
+		}
+
+		return a.UnmarshalText([]byte(strings.Join(names, actionSeparator)))
+	case bs[0] == '"':
+		var str string
+		if err = json.Unmarshal(bs, &str); err != nil {
+			return
+		}
+
+		return a.UnmarshalText([]byte(str))
+	default:
+		var n uint64
+		if err = json.Unmarshal(bs, &n); err != nil {
+			return
+		}
+
+		*a = Action(n)
+		return
+	}
+}
+
+func newActionRegistry() *actionRegistry {
+	var r actionRegistry
+	r.byName = make(map[string]Action)
+	r.byAction = make(map[Action]string)
+	r.set("none", ActionNone)
+	r.set("read", ActionRead)
+	r.set("write", ActionWrite)
+	r.set("delete", ActionDelete)
+	return &r
+}
+
+type actionRegistry struct {
+	mux      sync.RWMutex
+	byName   map[string]Action
+	byAction map[Action]string
+}
+
+func (r *actionRegistry) register(name string) (a Action, err error) {
+	if len(name) == 0 || strings.ContainsAny(name, actionSeparator+",[]\" ") {
+		err = ErrInvalidActionName
+		return
+	}
+
+	if _, err = strconv.ParseUint(name, 10, 64); err == nil {
+		// Numeric names are reserved for unnamed bits
+		err = ErrInvalidActionName
+		return
+	}
+
+	err = nil
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.byName[name]; ok {
+		err = ErrActionExists
+		return
+	}
+
+	if len(r.byAction) == 64 {
+		err = ErrActionLimitReached
+		return
+	}
+
+	a = Action(1) << len(r.byAction)
+	r.set(name, a)
+	return
+}
+
+func (r *actionRegistry) set(name string, a Action) {
+	r.byName[name] = a
+	r.byAction[a] = name
+}
+
+func (r *actionRegistry) get(name string) (a Action, ok bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	a, ok = r.byName[name]
+	return
+}
+
+func (r *actionRegistry) name(a Action) (name string, ok bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	name, ok = r.byAction[a]
+	return
+}
+
+func (r *actionRegistry) names() (names []string) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	for i := 0; i < len(r.byAction); i++ {
+		names = append(names, r.byAction[Action(1)<<i])
+	}
+
+	return
+}
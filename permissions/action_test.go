@@ -0,0 +1,93 @@
+package permissions
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRegisterAction(t *testing.T) {
+	publish, err := RegisterAction("publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = RegisterAction("publish"); err != ErrActionExists {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrActionExists, err)
+	}
+
+	if _, err = RegisterAction("read|write"); err != ErrInvalidActionName {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrInvalidActionName, err)
+	}
+
+	var a Action
+	if a, err = ParseAction("read|publish"); err != nil {
+		t.Fatal(err)
+	} else if a != ActionRead|publish {
+		t.Fatalf("invalid action, expected <%d> and received <%d>", ActionRead|publish, a)
+	}
+
+	if _, err = ParseAction("read|unknown"); err == nil {
+		t.Fatal("expected unknown action to fail parsing")
+	}
+}
+
+func TestAction_HasAll(t *testing.T) {
+	a := ActionRead | ActionWrite
+	if !a.HasAll(ActionRead | ActionWrite) {
+		t.Fatal("expected all actions to be included")
+	}
+
+	if a.HasAll(ActionRead | ActionDelete) {
+		t.Fatal("expected delete to not be included")
+	}
+
+	if !a.HasAny(ActionRead | ActionDelete) {
+		t.Fatal("expected read to be included")
+	}
+
+	g := Groups{"users": a}
+	if !g.Can("users", ActionRead|ActionWrite) {
+		t.Fatal("expected combined action mask to be allowed")
+	}
+}
+
+func TestAction_JSON(t *testing.T) {
+	type testcase struct {
+		value    string
+		expected Action
+	}
+
+	tcs := []testcase{
+		{value: `6`, expected: ActionRead | ActionWrite},
+		{value: `"read|write"`, expected: ActionRead | ActionWrite},
+		{value: `["read","delete"]`, expected: ActionRead | ActionDelete},
+	}
+
+	for _, tc := range tcs {
+		var a Action
+		if err := json.Unmarshal([]byte(tc.value), &a); err != nil {
+			t.Fatal(err)
+		} else if a != tc.expected {
+			t.Fatalf("invalid action for %s, expected <%v> and received <%v>", tc.value, tc.expected, a)
+		}
+	}
+
+	bs, err := json.Marshal(Groups{"users": ActionRead | ActionWrite})
+	if err != nil {
+		t.Fatal(err)
+	} else if string(bs) != `{"users":"read|write"}` {
+		t.Fatalf("invalid JSON, expected <%s> and received <%s>", `{"users":"read|write"}`, bs)
+	}
+}
+
+func TestAction_JSON_unknown(t *testing.T) {
+	// Resources referencing an unregistered action remain readable, the unknown action is skipped
+	var r Resource
+	if err := json.Unmarshal([]byte(`{"key":"posts","groups":{"users":"read|unregistered"}}`), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if a := r.Groups["users"]; a != ActionRead {
+		t.Fatalf("invalid action, expected <%v> and received <%v>", ActionRead, a)
+	}
+}
@@ -0,0 +1,151 @@
+package permissions
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the default maximum number of entries held by each authorization cache
+const DefaultCacheSize = 10000
+
+// CacheStats represents the metrics of an authorization cache
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// HitRate will return the ratio of lookups which were served from the cache
+func (c CacheStats) HitRate() float64 {
+	total := c.Hits + c.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(c.Hits) / float64(total)
+}
+
+// CacheMetrics represents the metrics of the authorization caches
+type CacheMetrics struct {
+	Resources   CacheStats `json:"resources"`
+	Memberships CacheStats `json:"memberships"`
+	Roles       CacheStats `json:"roles"`
+}
+
+// Total will return the combined metrics of all authorization caches
+func (c CacheMetrics) Total() (total CacheStats) {
+	for _, stats := range []CacheStats{c.Resources, c.Memberships, c.Roles} {
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Size += stats.Size
+	}
+
+	return
+}
+
+func newCache[T any](size int) *cache[T] {
+	var c cache[T]
+	c.size = size
+	c.entries = make(map[string]*list.Element)
+	c.order = list.New()
+	return &c
+}
+
+// cache is a bounded least-recently-used cache
+// Note: Values are only stored when no invalidation has occurred since the lookup began, this
+// prevents a read which raced a write from re-populating the cache with a stale value
+type cache[T any] struct {
+	mux sync.Mutex
+
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	// generation is incremented on every invalidation
+	generation uint64
+
+	stats CacheStats
+}
+
+type cacheEntry[T any] struct {
+	key   string
+	value T
+}
+
+// get will return the cached value for a key
+func (c *cache[T]) get(key string) (value T, ok bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var elem *list.Element
+	if elem, ok = c.entries[key]; !ok {
+		c.stats.Misses++
+		return
+	}
+
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+	value = elem.Value.(*cacheEntry[T]).value
+	return
+}
+
+// getGeneration will return the current generation, it must be captured before the value is read from the store
+func (c *cache[T]) getGeneration() uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.generation
+}
+
+// put will store a value retrieved during the provided generation
+func (c *cache[T]) put(key string, value T, generation uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.size <= 0 || generation != c.generation {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry[T]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry[T]{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[T]).key)
+		c.stats.Evictions++
+	}
+}
+
+// invalidate will remove the provided keys
+func (c *cache[T]) invalidate(keys ...string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation++
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// reset will remove all entries and set the maximum size
+func (c *cache[T]) reset(size int) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation++
+	c.size = size
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *cache[T]) getStats() (stats CacheStats) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	stats = c.stats
+	stats.Size = c.order.Len()
+	return
+}
@@ -0,0 +1,78 @@
+package permissions
+
+import "testing"
+
+func TestCache(t *testing.T) {
+	c := newCache[int](2)
+	gen := c.getGeneration()
+	c.put("a", 1, gen)
+	c.put("b", 2, gen)
+	c.get("a")
+	c.put("c", 3, gen)
+
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Fatalf("invalid value, expected <%d> and received <%d>", 1, v)
+	}
+
+	c.invalidate("a")
+	c.put("a", 4, gen)
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected value read prior to invalidation to not be stored")
+	}
+
+	stats := c.getStats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Evictions != 1 {
+		t.Fatalf("invalid stats, received %+v", stats)
+	}
+}
+
+func TestPermissions_cache(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("posts", "users", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if !p.Can(testUser1, "posts", ActionRead) {
+			t.Fatal(testErrCannot)
+		}
+	}
+
+	if stats := p.CacheStats().Total(); stats.Hits == 0 {
+		t.Fatalf("expected cache hits, received %+v", stats)
+	}
+
+	if err = p.UnsetPermissions("posts", "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Can(testUser1, "posts", ActionRead) {
+		t.Fatal(testErrCan)
+	}
+
+	if err = p.SetPermissions("posts", "writers", ActionWrite); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Can(testUser1, "posts", ActionWrite) {
+		t.Fatal(testErrCan)
+	}
+
+	if _, err = g.AddGroups(testUser1, "writers"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Can(testUser1, "posts", ActionWrite) {
+		t.Fatal(testErrCannot)
+	}
+}
@@ -0,0 +1,192 @@
+package permissions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	// ReasonAllowed is the decision reason when a grant includes the action
+	ReasonAllowed = "allowed"
+	// ReasonGroupsError is the decision reason when the groups of the user could not be retrieved
+	ReasonGroupsError = "error getting groups"
+	// ReasonNoGroups is the decision reason when the user does not belong to any groups
+	ReasonNoGroups = "user has no groups"
+	// ReasonResourceNotFound is the decision reason when neither the resource nor any ancestors exist
+	ReasonResourceNotFound = "resource not found"
+	// ReasonNoMatchingGroup is the decision reason when no group of the user holds a grant for the resource
+	ReasonNoMatchingGroup = "no matching group"
+	// ReasonActionNotGranted is the decision reason when the user's grants do not include the action
+	ReasonActionNotGranted = "action not granted"
+	// ReasonActionDenied is the decision reason when a deny held by one of the user's groups includes the action
+	ReasonActionDenied = "action denied"
+)
+
+// Grant represents the actions a group holds on a resource key
+type Grant struct {
+	ResourceKey string `json:"resourceKey"`
+	Group       string `json:"group"`
+	Role        string `json:"role,omitempty"`
+	Actions     Action `json:"actions"`
+	// Deny is true when the actions are denied rather than granted
+	Deny bool `json:"deny,omitempty"`
+}
+
+// Decision represents the explanation of a permissions check
+type Decision struct {
+	UserID      string `json:"userID"`
+	ResourceKey string `json:"resourceKey"`
+	Action      Action `json:"action"`
+
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+
+	// Groups are the groups of the user which were evaluated
+	Groups []string `json:"groups"`
+	// ResourceKeys are the resource keys which were evaluated, most specific first
+	ResourceKeys []string `json:"resourceKeys"`
+	// Grant is the grant which allowed the action
+	Grant *Grant `json:"grant,omitempty"`
+	// Grants are the grants held by the user on the deciding resource key
+	Grants []Grant `json:"grants,omitempty"`
+	// Denies are the denies held by the user on any of the evaluated resource keys
+	Denies []Grant `json:"denies,omitempty"`
+
+	Errors []string `json:"errors,omitempty"`
+}
+
+func (d *Decision) pushError(err error) {
+	d.Errors = append(d.Errors, err.Error())
+}
+
+// explain will evaluate whether a user (userID) can perform an action on a resource key
+// Hierarchical keys inherit the grants of their ancestors. The most specific resource which
+// grants any of the user's groups decides, so exact grants take priority over inherited grants.
+// Denies are evaluated across all of the user's groups on every candidate key, a deny which
+// includes any of the requested actions beats any grant.
+func (p *Permissions) explain(l *lookup, userID, resourceKey string, action Action) (d Decision) {
+	d.UserID = userID
+	d.ResourceKey = resourceKey
+	d.Action = action
+
+	groups, err := l.getGroups(userID)
+	if err != nil {
+		d.Reason = ReasonGroupsError
+		d.pushError(err)
+		return
+	}
+
+	// The groups are shared with the memberships cache, the decision holds a copy
+	d.Groups = slices.Clone(groups)
+
+	if len(d.Groups) == 0 {
+		d.Reason = ReasonNoGroups
+		return
+	}
+
+	d.Reason = ReasonResourceNotFound
+	var decided bool
+	for _, key := range getCandidateKeys(resourceKey) {
+		d.ResourceKeys = append(d.ResourceKeys, key)
+
+		var e *Resource
+		switch e, err = l.getResource(key); {
+		case err == nil:
+		case errors.Is(err, ErrResourceNotFound):
+			continue
+		default:
+			d.pushError(fmt.Errorf("error getting resource <%s>: %v", key, err))
+			continue
+		}
+
+		if d.Reason == ReasonResourceNotFound {
+			d.Reason = ReasonNoMatchingGroup
+		}
+
+		d.Denies = append(d.Denies, getDenies(e, d.Groups)...)
+		if decided {
+			// Ancestors are only evaluated for denies once a resource has decided
+			continue
+		}
+
+		for _, group := range d.Groups {
+			if !e.HasGrant(group) {
+				continue
+			}
+
+			var grant Grant
+			if grant, err = getGrant(l, e, group); err != nil {
+				d.pushError(err)
+			}
+
+			d.Grants = append(d.Grants, grant)
+			if grant.Actions.HasAll(action) && !d.Allowed {
+				d.Allowed = true
+				d.Reason = ReasonAllowed
+				d.Grant = &grant
+			}
+		}
+
+		if decided = len(d.Grants) > 0; decided && !d.Allowed {
+			d.Reason = ReasonActionNotGranted
+		}
+	}
+
+	for i, deny := range d.Denies {
+		if !deny.Actions.HasAny(action) {
+			continue
+		}
+
+		d.Allowed = false
+		d.Reason = ReasonActionDenied
+		d.Grant = &d.Denies[i]
+		break
+	}
+
+	return
+}
+
+// getDenies will return the denies held by the provided groups on a resource
+func getDenies(e *Resource, groups []string) (denies []Grant) {
+	for _, group := range groups {
+		actions, ok := e.Denies.Get(group)
+		if !ok {
+			continue
+		}
+
+		denies = append(denies, makeDeny(e.Key, group, actions))
+	}
+
+	return
+}
+
+func makeDeny(resourceKey, group string, actions Action) (g Grant) {
+	g.ResourceKey = resourceKey
+	g.Group = group
+	g.Actions = actions
+	g.Deny = true
+	return
+}
+
+// getGrant will return the grant of a group on a resource, including the actions of its role
+// Note: When the role cannot be retrieved, the grant is returned with the directly assigned actions
+func getGrant(l *lookup, e *Resource, group string) (g Grant, err error) {
+	g.ResourceKey = e.Key
+	g.Group = group
+	g.Actions, _ = e.Get(group)
+
+	var ok bool
+	if g.Role, ok = e.GetRole(group); !ok {
+		return
+	}
+
+	var role *Role
+	if role, err = l.getRole(g.Role); err != nil {
+		err = fmt.Errorf("error getting role <%s>: %v", g.Role, err)
+		return
+	}
+
+	g.Actions |= role.Actions
+	return
+}
@@ -1,23 +1,10 @@
 package permissions
 
+import "sort"
+
 // Groups represents a resource's group list (available actions keyed by group)
 type Groups map[string]Action
 
-func (g Groups) canRead(group string) bool {
-	act := g[group]
-	return act&ActionRead != 0
-}
-
-func (g Groups) canWrite(group string) bool {
-	act := g[group]
-	return act&ActionWrite != 0
-}
-
-func (g Groups) canDelete(group string) bool {
-	act := g[group]
-	return act&ActionDelete != 0
-}
-
 // Get will get the actions available to a given group
 func (g Groups) Get(group string) (actions Action, ok bool) {
 	actions, ok = g[group]
@@ -31,24 +18,14 @@ func (g Groups) Has(group string) (ok bool) {
 }
 
 // Can will check to see if a group can perform a given action
+// Note: When multiple actions are provided, the group must be able to perform all of them
 func (g Groups) Can(group string, action Action) (ok bool) {
-	switch action {
-	case ActionRead:
-		return g.canRead(group)
-	case ActionWrite:
-		return g.canWrite(group)
-	case ActionDelete:
-		return g.canDelete(group)
-	}
-
-	return
+	return g[group].HasAll(action)
 }
 
-// Set will set the actions available to a given group
+// Set will set the actions available to a given group, replacing any current actions
 func (g Groups) Set(group string, actions Action) (ok bool) {
-	var currentActions Action
-	currentActions, _ = g.Get(group)
-	if currentActions|actions == currentActions {
+	if current, exists := g.Get(group); exists && current == actions {
 		return false
 	}
 
@@ -64,3 +41,12 @@ func (g Groups) Remove(group string) (ok bool) {
 
 	return
 }
+
+func (g Groups) getNames() (names []string) {
+	for group := range g {
+		names = append(names, group)
+	}
+
+	sort.Strings(names)
+	return
+}
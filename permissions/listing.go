@@ -0,0 +1,142 @@
+package permissions
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mojura/mojura"
+	"github.com/mojura/mojura/filters"
+)
+
+// ListPageSize is the maximum number of resource IDs returned by a single ListAccessible call
+const ListPageSize = 100
+
+// ListAccessible will return the IDs of the resources (by resource name) a user can perform an action on
+// Results are sorted by resource ID and paginated, provide the returned cursor to retrieve the following
+// page. An empty cursor is returned when there are no more results.
+// Note: Only resources with a direct grant to one of the user's groups are listed, access inherited from
+// ancestors or wildcards is not included. Hierarchical keys are listed by their final segment (e.g. doc::7).
+// Note: Resource IDs are unescaped, see EscapeResourceID
+func (p *Permissions) ListAccessible(userID, resourceName string, action Action, cursor string) (resourceIDs []string, next string, err error) {
+	l := p.newLookup(true)
+	if err = p.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		l.txn = txn
+		resourceIDs, next, err = p.listAccessible(&l, userID, resourceName, action, EscapeResourceID(cursor))
+		return
+	}); err != nil {
+		return
+	}
+
+	for i, resourceID := range resourceIDs {
+		resourceIDs[i] = UnescapeResourceID(resourceID)
+	}
+
+	next = UnescapeResourceID(next)
+	return
+}
+
+// ListGrantees will return the grants held directly on a resource key, sorted by group
+// Denies follow the grants, see Grant.Deny
+// Note: Grants inherited from ancestors or wildcards are not included
+func (p *Permissions) ListGrantees(resourceKey string) (grants []Grant, err error) {
+	l := p.newLookup(true)
+	err = p.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		l.txn = txn
+
+		var r *Resource
+		if r, err = l.getResource(resourceKey); err != nil {
+			return
+		}
+
+		for _, group := range r.GetGrantees() {
+			var grant Grant
+			if grant, err = getGrant(&l, r, group); err != nil {
+				return
+			}
+
+			grants = append(grants, grant)
+		}
+
+		for _, group := range r.Denies.getNames() {
+			actions, _ := r.Denies.Get(group)
+			grants = append(grants, makeDeny(r.Key, group, actions))
+		}
+
+		return
+	})
+
+	return
+}
+
+func (p *Permissions) listAccessible(l *lookup, userID, resourceName string, action Action, cursor string) (resourceIDs []string, next string, err error) {
+	var groups []string
+	if groups, err = l.getGroups(userID); err != nil {
+		return
+	}
+
+	// The first ListPageSize+1 accessible IDs of each group include the first ListPageSize+1 accessible IDs overall
+	accessible := make(map[string]struct{})
+	for _, group := range groups {
+		if err = p.listGroupAccessible(l, userID, group, resourceName, action, cursor, accessible); err != nil {
+			return
+		}
+	}
+
+	sorted := make([]string, 0, len(accessible))
+	for resourceID := range accessible {
+		sorted = append(sorted, resourceID)
+	}
+
+	sort.Strings(sorted)
+	if len(sorted) > ListPageSize {
+		sorted = sorted[:ListPageSize]
+		next = sorted[ListPageSize-1]
+	}
+
+	resourceIDs = sorted
+	return
+}
+
+// listGroupAccessible will add the first ListPageSize+1 resource IDs (after the cursor) granted to a group which
+// the user can perform an action on to the accessible set. The grantee index is seeked from the cursor in resource ID order.
+func (p *Permissions) listGroupAccessible(l *lookup, userID, group, resourceName string, action Action, cursor string, accessible map[string]struct{}) (err error) {
+	prefix := newGranteeIndexPrefix(group, resourceName)
+	rangeStart := prefix
+	if len(cursor) > 0 {
+		// Start directly after the cursor, the NUL suffix sorts before any longer resource ID
+		rangeStart = prefix + cursor + "\x00"
+	}
+
+	// Resource IDs are UTF-8, so no key within the prefix sorts after 0xff
+	rangeEnd := prefix + "\xff"
+	filter := filters.ComparisonWithRange(relationshipGrantees, rangeStart, rangeEnd, func(relationshipID string) (ok bool, err error) {
+		ok = relationshipID >= rangeStart && relationshipID <= rangeEnd
+		return
+	})
+
+	var count int
+	var last string
+	err = l.txn.ForEach(func(_ string, r *Resource) (err error) {
+		_, resourceID := splitResourceKey(r.Key)
+		if resourceID == last {
+			// Hierarchical keys nested beneath different parents share a resource ID, it is already accessible
+			return
+		}
+
+		if _, ok := accessible[resourceID]; !ok {
+			if !p.explain(l, userID, r.Key, action).Allowed {
+				return
+			}
+
+			accessible[resourceID] = struct{}{}
+		}
+
+		last = resourceID
+		if count++; count > ListPageSize {
+			return mojura.Break
+		}
+
+		return
+	}, mojura.NewFilteringOpts(filter))
+	return
+}
@@ -0,0 +1,95 @@
+package permissions
+
+import (
+	"github.com/mojura/mojura"
+)
+
+// newLookup will return a lookup for the provided transaction
+// Note: Cache generations are captured before the transaction begins, see cache
+func (p *Permissions) newLookup(cached bool) (l lookup) {
+	l.p = p
+	l.cached = cached
+	if cached {
+		l.resourcesGeneration = p.resources.getGeneration()
+		l.membershipsGeneration = p.memberships.getGeneration()
+		l.rolesGeneration = p.roles.getGeneration()
+	}
+
+	return
+}
+
+// lookup resolves the resources, group memberships and roles used to evaluate a decision
+// Note: Lookups within write transactions must not be cached, uncommitted values could be stored
+type lookup struct {
+	p   *Permissions
+	txn *mojura.Transaction[*Resource]
+
+	cached bool
+
+	resourcesGeneration   uint64
+	membershipsGeneration uint64
+	rolesGeneration       uint64
+}
+
+func (l *lookup) getResource(resourceKey string) (r *Resource, err error) {
+	if !l.cached {
+		return l.p.getByKey(l.txn, resourceKey)
+	}
+
+	var ok bool
+	if r, ok = l.p.resources.get(resourceKey); ok {
+		if r == nil {
+			err = ErrResourceNotFound
+		}
+
+		return
+	}
+
+	switch r, err = l.p.getByKey(l.txn, resourceKey); err {
+	case nil, ErrResourceNotFound:
+		// Missing resources are cached as well, ancestors are frequently absent
+		l.p.resources.put(resourceKey, r, l.resourcesGeneration)
+	}
+
+	return
+}
+
+func (l *lookup) getGroups(userID string) (groups []string, err error) {
+	if !l.cached {
+		return l.p.g.Get(userID)
+	}
+
+	var ok bool
+	if groups, ok = l.p.memberships.get(userID); ok {
+		return
+	}
+
+	if groups, err = l.p.g.Get(userID); err != nil {
+		return
+	}
+
+	l.p.memberships.put(userID, groups, l.membershipsGeneration)
+	return
+}
+
+func (l *lookup) getRole(name string) (role *Role, err error) {
+	if !l.cached {
+		return l.p.GetRole(name)
+	}
+
+	var ok bool
+	if role, ok = l.p.roles.get(name); ok {
+		if role == nil {
+			err = ErrRoleNotFound
+		}
+
+		return
+	}
+
+	switch role, err = l.p.GetRole(name); err {
+	case nil, ErrRoleNotFound:
+		l.p.roles.put(name, role, l.rolesGeneration)
+	}
+
+	return
+}
@@ -2,6 +2,7 @@ package permissions
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"github.com/gdbu/errors"
@@ -17,10 +18,15 @@ const (
 	ErrResourceNotFound = errors.Error("resource not found")
 	// ErrGroupNotFound is returned when a requested group cannot be found
 	ErrGroupNotFound = errors.Error("group not found")
+	// ErrRoleNotFound is returned when a requested role cannot be found
+	ErrRoleNotFound = errors.Error("role not found")
+	// ErrInvalidRoleName is returned when a role name is empty
+	ErrInvalidRoleName = errors.Error("invalid role name, cannot be empty")
 )
 
 const (
 	relationshipResourceKeys = "resourceKeys"
+	relationshipGrantees     = "grantees"
 )
 
 // New will return a new instance of Permissions
@@ -28,10 +34,36 @@ func New(opts mojura.Opts) (pp *Permissions, err error) {
 	opts.Name = "permissions"
 
 	var p Permissions
-	if p.c, err = mojura.New[*Resource](opts, relationshipResourceKeys); err != nil {
+	if p.c, err = mojura.New[*Resource](opts, relationshipResourceKeys, relationshipGrantees); err != nil {
 		return
 	}
 
+	opts.Name = "roles"
+	if p.r, err = mojura.New[*Role](opts, relationshipRoleNames); err != nil {
+		return
+	}
+
+	cacheSize := DefaultCacheSize
+	if opts.IsMirror {
+		// Mirrors are written to by replication, the cache cannot be invalidated
+		cacheSize = 0
+	}
+
+	p.resources = newCache[*Resource](cacheSize)
+	p.memberships = newCache[[]string](cacheSize)
+	p.roles = newCache[*Role](cacheSize)
+
+	if !opts.IsMirror {
+		if err = p.migrate(); err != nil {
+			err = fmt.Errorf("error migrating permissions: %v", err)
+			return
+		}
+
+		if err = p.setDefaultRoles(); err != nil {
+			return
+		}
+	}
+
 	pp = &p
 	return
 }
@@ -39,7 +71,13 @@ func New(opts mojura.Opts) (pp *Permissions, err error) {
 // Permissions manages permissions
 type Permissions struct {
 	c *mojura.Mojura[*Resource]
+	r *mojura.Mojura[*Role]
 	g *groups.Groups
+
+	// Authorization caches, see SetCacheSize
+	resources   *cache[*Resource]
+	memberships *cache[[]string]
+	roles       *cache[*Role]
 }
 
 // Get will get the resource entry for a given resource ID
@@ -70,11 +108,13 @@ func (p *Permissions) ForEach(fn func(*Resource) error, opts *mojura.FilteringOp
 }
 
 // SetPermissions will set the permissions for a resource key being accessed by given group
+// Note: The current actions of the group are replaced, previously granted actions are not retained
 func (p *Permissions) SetPermissions(resourceKey, group string, actions Action) (err error) {
 	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
 		return p.setPermissions(txn, resourceKey, group, actions)
 	})
 
+	p.resources.invalidate(resourceKey)
 	return
 }
 
@@ -90,6 +130,7 @@ func (p *Permissions) SetMultiPermissions(resourceKey string, pairs ...Pair) (er
 		return
 	})
 
+	p.resources.invalidate(resourceKey)
 	return
 }
 
@@ -99,6 +140,7 @@ func (p *Permissions) UnsetPermissions(resourceKey, group string) (err error) {
 		return p.unsetPermissions(txn, resourceKey, group)
 	})
 
+	p.resources.invalidate(resourceKey)
 	return
 }
 
@@ -114,14 +156,40 @@ func (p *Permissions) UnsetMultiPermissions(resourceKey string, groups ...string
 		return
 	})
 
+	p.resources.invalidate(resourceKey)
 	return
 }
 
-// Can will return if a user (userID) can perform a given action on a provided resource id
-// Note: This isn't done as a transaction because it's two GET requests which don't need to block
+// SetDenyPermissions will set the actions denied to a given group for a resource key
+// Note: Denies take priority over grants, see Can
+func (p *Permissions) SetDenyPermissions(resourceKey, group string, actions Action) (err error) {
+	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		return p.setDenyPermissions(txn, resourceKey, group, actions)
+	})
+
+	p.resources.invalidate(resourceKey)
+	return
+}
+
+// UnsetDenyPermissions will remove the actions denied to a given group for a resource key
+func (p *Permissions) UnsetDenyPermissions(resourceKey, group string) (err error) {
+	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		return p.unsetDenyPermissions(txn, resourceKey, group)
+	})
+
+	p.resources.invalidate(resourceKey)
+	return
+}
+
+// Can will return if a user (userID) can perform a given action on a provided resource key
+// Note: Hierarchical keys (e.g. project::42/doc::7) inherit the permissions of their ancestors
+// and wildcards (e.g. project::42/*), exact grants take priority. A deny held by any of the
+// user's groups on the resource key or its ancestors takes priority over all grants.
 func (p *Permissions) Can(userID, resourceKey string, action Action) (can bool) {
+	l := p.newLookup(true)
 	if err := p.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
-		can = p.can(txn, userID, resourceKey, action)
+		l.txn = txn
+		can = p.explain(&l, userID, resourceKey, action).Allowed
 		return
 	}); err != nil {
 		log.Printf("Permissions.Can(): Error checking can state: %v", err)
@@ -131,6 +199,22 @@ func (p *Permissions) Can(userID, resourceKey string, action Action) (can bool)
 	return
 }
 
+// Explain will return the decision of whether a user (userID) can perform a given action on a provided resource key
+// Note: Unlike Can, the groups evaluated, the deciding grants and any errors encountered are included
+func (p *Permissions) Explain(userID, resourceKey string, action Action) (d Decision) {
+	l := p.newLookup(true)
+	if err := p.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		l.txn = txn
+		d = p.explain(&l, userID, resourceKey, action)
+		return
+	}); err != nil {
+		d.pushError(err)
+		return
+	}
+
+	return
+}
+
 // Has will return whether or not an ID has a particular group associated with it
 func (p *Permissions) Has(resourceID, group string) (has bool) {
 	if err := p.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
@@ -150,29 +234,98 @@ func (p *Permissions) RemoveResource(resourceKey string) (err error) {
 		return p.removeResource(txn, resourceKey)
 	})
 
+	p.resources.invalidate(resourceKey)
+	return
+}
+
+// RemoveGroup will remove the grants, roles and denies held by a group on every resource
+// Note: All resources are scanned, this is intended for removing a principal (e.g. a deleted service account)
+func (p *Permissions) RemoveGroup(group string) (err error) {
+	var touched []string
+	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		var rs []*Resource
+		if err = txn.ForEach(func(_ string, r *Resource) (err error) {
+			removed := r.Remove(group)
+			removed = r.RemoveRole(group) || removed
+			removed = r.RemoveDeny(group) || removed
+			if removed {
+				rs = append(rs, r)
+			}
+
+			return
+		}, nil); err != nil {
+			return
+		}
+
+		// Resources are updated once iteration has completed
+		for _, r := range rs {
+			if _, err = txn.Put(r.ID, r); err != nil {
+				return
+			}
+
+			touched = append(touched, r.Key)
+		}
+
+		return
+	})
+
+	p.resources.invalidate(touched...)
 	return
 }
 
+// Reindex will rebuild the resources relationships
+// Note: Resources persisted before the current grantees index are reindexed by New
+func (p *Permissions) Reindex() (err error) {
+	return p.c.Reindex(context.Background())
+}
+
 // Transaction will initialize a transaction for all methods to be executed under
 func (p *Permissions) Transaction(fn func(*Transaction) error) (err error) {
+	var touched []string
 	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
 		t := newTransaction(txn, p)
 		err = fn(&t)
 		t.txn = nil
+		touched = t.touched
 		return
 	})
 
+	p.resources.invalidate(touched...)
 	return
 }
 
 // SetGroups will set the groups controller
+// Note: Cached group memberships are invalidated when the groups of a user change
 func (p *Permissions) SetGroups(g *groups.Groups) {
 	p.g = g
+	p.memberships.reset(p.memberships.size)
+	g.OnChange(func(userID string) {
+		p.memberships.invalidate(userID)
+	})
+}
+
+// SetCacheSize will clear the authorization caches and set the maximum number of entries each may hold
+// Note: A size of zero disables caching
+func (p *Permissions) SetCacheSize(size int) {
+	p.resources.reset(size)
+	p.memberships.reset(size)
+	p.roles.reset(size)
+}
+
+// CacheStats will return the metrics of the authorization caches
+func (p *Permissions) CacheStats() (stats CacheMetrics) {
+	stats.Resources = p.resources.getStats()
+	stats.Memberships = p.memberships.getStats()
+	stats.Roles = p.roles.getStats()
+	return
 }
 
 // Close will close permissions
 func (p *Permissions) Close() (err error) {
-	return p.c.Close()
+	var errs errors.ErrorList
+	errs.Push(p.c.Close())
+	errs.Push(p.r.Close())
+	return errs.Err()
 }
 
 func (p *Permissions) setPermissions(txn *mojura.Transaction[*Resource], resourceKey, group string, actions Action) (err error) {
@@ -203,6 +356,34 @@ func (p *Permissions) unsetPermissions(txn *mojura.Transaction[*Resource], resou
 	return
 }
 
+func (p *Permissions) setDenyPermissions(txn *mojura.Transaction[*Resource], resourceKey, group string, actions Action) (err error) {
+	var r *Resource
+	if r, err = p.getOrCreateByKey(txn, resourceKey); err != nil {
+		return
+	}
+
+	if !r.SetDeny(group, actions) {
+		return
+	}
+
+	_, err = txn.Put(r.ID, r)
+	return
+}
+
+func (p *Permissions) unsetDenyPermissions(txn *mojura.Transaction[*Resource], resourceKey, group string) (err error) {
+	var r *Resource
+	if r, err = p.getByKey(txn, resourceKey); err != nil {
+		return
+	}
+
+	if !r.RemoveDeny(group) {
+		return
+	}
+
+	_, err = txn.Put(r.ID, r)
+	return
+}
+
 func (p *Permissions) removeResource(txn *mojura.Transaction[*Resource], resourceKey string) (err error) {
 	var r *Resource
 	if r, err = p.getByKey(txn, resourceKey); err != nil {
@@ -239,42 +420,61 @@ func (p *Permissions) getOrCreateByKey(txn *mojura.Transaction[*Resource], resou
 	return txn.New(&r)
 }
 
-// Can will return if a user (userID) can perform a given action on a provided resource id
-// Note: This isn't done as a transaction because it's two GET requests which don't need to block
+// can will return if a user (userID) can perform a given action on a provided resource key
+// Note: The authorization cache is bypassed, the transaction may contain uncommitted writes
 func (p *Permissions) can(txn *mojura.Transaction[*Resource], userID, resourceKey string, action Action) (can bool) {
+	l := p.newLookup(false)
+	l.txn = txn
+	return p.explain(&l, userID, resourceKey, action).Allowed
+}
+
+// Has will return whether or not an ID has a particular group associated with it
+func (p *Permissions) has(txn *mojura.Transaction[*Resource], resourceID, group string) (ok bool) {
 	var (
-		e      *Resource
-		groups []string
-		err    error
+		e   *Resource
+		err error
 	)
 
-	if e, err = p.getByKey(txn, resourceKey); err != nil {
+	if e, err = txn.Get(resourceID); err != nil {
 		return
 	}
 
-	if groups, err = p.g.Get(userID); err != nil {
-		return
-	}
+	return e.Has(group)
+}
 
-	for _, group := range groups {
-		if can = e.Can(group, action); can {
-			return
-		}
+// migrate will reindex the resources relationships when the grantees index is missing
+// Note: Resources persisted before the grantees index (or its current format) would otherwise not be listed by ListAccessible
+func (p *Permissions) migrate() (err error) {
+	var missing bool
+	if err = p.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		missing, err = isGranteesIndexMissing(txn)
+		return
+	}); err != nil || !missing {
+		return
 	}
 
-	return
+	log.Println("Permissions: grantees index is missing, reindexing resources")
+	return p.Reindex()
 }
 
-// Has will return whether or not an ID has a particular group associated with it
-func (p *Permissions) has(txn *mojura.Transaction[*Resource], resourceID, group string) (ok bool) {
-	var (
-		e   *Resource
-		err error
-	)
+// isGranteesIndexMissing will return whether or not the first indexable resource is absent from the grantees index
+func isGranteesIndexMissing(txn *mojura.Transaction[*Resource]) (missing bool, err error) {
+	var keys []string
+	if err = txn.ForEach(func(_ string, r *Resource) (err error) {
+		if keys = r.getGranteeIndexKeys(); len(keys) == 0 {
+			return
+		}
 
-	if e, err = txn.Get(resourceID); err != nil {
+		return mojura.Break
+	}, nil); err != nil || len(keys) == 0 {
 		return
 	}
 
-	return e.Has(group)
+	filter := filters.Match(relationshipGrantees, keys[0])
+	switch _, err = txn.GetFirst(mojura.NewFilteringOpts(filter)); err {
+	case mojura.ErrEntryNotFound:
+		return true, nil
+	default:
+		return
+	}
 }
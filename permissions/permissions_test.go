@@ -1,6 +1,7 @@
 package permissions
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
@@ -20,6 +21,38 @@ const (
 	testUser3 = "TEST_USER_3"
 )
 
+// newTestPermissions will return permissions and groups stored within a temporary directory
+func newTestPermissions(t *testing.T) (p *Permissions, g *groups.Groups) {
+	var (
+		opts mojura.Opts
+		err  error
+	)
+
+	opts.Dir = t.TempDir()
+	if p, err = New(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := p.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if g, err = groups.New(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := g.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	p.SetGroups(g)
+	return
+}
+
 func TestPermissions(t *testing.T) {
 	var (
 		p   *Permissions
@@ -92,6 +125,459 @@ func TestPermissions(t *testing.T) {
 	}
 }
 
+func TestPermissions_hierarchical(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "project-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = g.AddGroups(testUser2, "project-42-readers"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("project::42", "project-42", ActionRead|ActionWrite); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("project::42/*", "project-42-readers", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("project::42/doc::8", "project-42", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Can(testUser1, "project::42/doc::7", ActionWrite) {
+		t.Fatal(testErrCannot)
+	}
+
+	if !p.Can(testUser1, "project::42/doc::7/comment::1", ActionWrite) {
+		t.Fatal(testErrCannot)
+	}
+
+	if p.Can(testUser1, "project::42/doc::8", ActionWrite) {
+		// Exact grant takes priority over the inherited project grant
+		t.Fatal(testErrCan)
+	}
+
+	if !p.Can(testUser2, "project::42/doc::7", ActionRead) {
+		t.Fatal(testErrCannot)
+	}
+
+	if p.Can(testUser2, "project::42", ActionRead) {
+		// Wildcards only apply to descendants
+		t.Fatal(testErrCan)
+	}
+
+	if p.Can(testUser1, "project::43/doc::7", ActionRead) {
+		t.Fatal(testErrCan)
+	}
+}
+
+func TestPermissions_roles(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "editors"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetRolePermissions("posts", "editors", "unknown"); err != ErrRoleNotFound {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrRoleNotFound, err)
+	}
+
+	if err = p.SetRolePermissions("posts", "editors", RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Can(testUser1, "posts", ActionWrite) {
+		t.Fatal(testErrCannot)
+	}
+
+	if p.Can(testUser1, "posts", ActionDelete) {
+		t.Fatal(testErrCan)
+	}
+
+	if err = p.SetRole(RoleEditor, ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Can(testUser1, "posts", ActionWrite) {
+		// Redefined role no longer grants write
+		t.Fatal(testErrCan)
+	}
+
+	if !p.Can(testUser1, "posts", ActionRead) {
+		t.Fatal(testErrCannot)
+	}
+}
+
+func TestPermissions_Explain(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("posts", "users", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	type testcase struct {
+		userID      string
+		resourceKey string
+		action      Action
+		allowed     bool
+		reason      string
+	}
+
+	tcs := []testcase{
+		{userID: testUser1, resourceKey: "posts", action: ActionRead, allowed: true, reason: ReasonAllowed},
+		{userID: testUser1, resourceKey: "posts", action: ActionWrite, reason: ReasonActionNotGranted},
+		{userID: testUser1, resourceKey: "comments", action: ActionRead, reason: ReasonResourceNotFound},
+		{userID: testUser2, resourceKey: "posts", action: ActionRead, reason: ReasonNoGroups},
+	}
+
+	for _, tc := range tcs {
+		d := p.Explain(tc.userID, tc.resourceKey, tc.action)
+		if d.Allowed != tc.allowed || d.Reason != tc.reason {
+			t.Fatalf("invalid decision for %s on %s, expected <%v/%s> and received <%v/%s>", tc.userID, tc.resourceKey, tc.allowed, tc.reason, d.Allowed, d.Reason)
+		}
+	}
+
+	d := p.Explain(testUser1, "posts", ActionRead)
+	if d.Grant == nil || d.Grant.Group != "users" {
+		t.Fatalf("invalid grant, expected group <%s> and received %+v", "users", d.Grant)
+	}
+
+	// Modifying the decision groups must not affect the memberships cache
+	d.Groups[0] = "admins"
+	if d = p.Explain(testUser1, "posts", ActionRead); !d.Allowed || d.Groups[0] != "users" {
+		t.Fatalf("invalid decision, expected groups <%v> and received <%v>", []string{"users"}, d.Groups)
+	}
+}
+
+func TestPermissions_ListAccessible(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "users", "editors"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < ListPageSize+1; i++ {
+		if err = p.SetPermissions(fmt.Sprintf("posts::%03d", i), "users", ActionRead); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = p.SetPermissions("posts::000", "editors", ActionRead|ActionWrite); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetRolePermissions("project::42/doc::7", "editors", RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("comments::1", "others", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ids  []string
+		next string
+	)
+
+	if ids, next, err = p.ListAccessible(testUser1, "posts", ActionRead, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != ListPageSize || ids[0] != "000" || next != ids[len(ids)-1] {
+		t.Fatalf("invalid first page, received %d IDs and a cursor of <%s>", len(ids), next)
+	}
+
+	if ids, next, err = p.ListAccessible(testUser1, "posts", ActionRead, next); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || next != "" {
+		t.Fatalf("invalid second page, expected one ID and no cursor and received %v and <%s>", ids, next)
+	}
+
+	if ids, _, err = p.ListAccessible(testUser1, "posts", ActionWrite, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || ids[0] != "000" {
+		t.Fatalf("invalid writable posts, expected <000> and received %v", ids)
+	}
+
+	if ids, _, err = p.ListAccessible(testUser1, "doc", ActionWrite, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || ids[0] != "7" {
+		t.Fatalf("invalid writable docs, expected <7> and received %v", ids)
+	}
+
+	if ids, _, err = p.ListAccessible(testUser1, "comments", ActionRead, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 {
+		t.Fatalf("invalid comments, expected none and received %v", ids)
+	}
+
+	var grants []Grant
+	if grants, err = p.ListGrantees("posts::000"); err != nil {
+		t.Fatal(err)
+	} else if len(grants) != 2 || grants[0].Group != "editors" || grants[1].Group != "users" {
+		t.Fatalf("invalid grantees, expected editors and users and received %+v", grants)
+	}
+
+	if grants, err = p.ListGrantees("project::42/doc::7"); err != nil {
+		t.Fatal(err)
+	} else if len(grants) != 1 || grants[0].Role != RoleEditor || !grants[0].Actions.HasAll(ActionRead|ActionWrite) {
+		t.Fatalf("invalid grantees, expected the editor role and received %+v", grants)
+	}
+}
+
+func TestPermissions_ListAccessible_cursor(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "users", "editors"); err != nil {
+		t.Fatal(err)
+	}
+
+	// IDs sharing a prefix with the cursor, granted across both groups, with denied IDs in between
+	grants := []Pair{
+		{Group: "users", Actions: ActionRead},
+		{Group: "editors", Actions: ActionRead},
+	}
+
+	for i, resourceID := range []string{"a", "a0", "a/b", "b0", "c"} {
+		if err = p.SetPermissions("files::"+EscapeResourceID(resourceID), grants[i%2].Group, grants[i%2].Actions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = p.SetPermissions("files::b", "users", ActionWrite); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ids  []string
+		next string
+	)
+
+	if ids, next, err = p.ListAccessible(testUser1, "files", ActionRead, "a"); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(ids) != "[a/b a0 b0 c]" || len(next) > 0 {
+		t.Fatalf("invalid page, expected <[a/b a0 b0 c]> and received <%v> with a cursor of <%s>", ids, next)
+	}
+
+	if ids, _, err = p.ListAccessible(testUser1, "files", ActionRead, "a0"); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(ids) != "[b0 c]" {
+		t.Fatalf("invalid page, expected <[b0 c]> and received <%v>", ids)
+	}
+}
+
+func TestPermissions_migrate(t *testing.T) {
+	var (
+		p   *Permissions
+		g   *groups.Groups
+		err error
+	)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	opts.Name = "permissions"
+
+	var legacy *mojura.Mojura[*legacyResource]
+	if legacy, err = mojura.New[*legacyResource](opts, relationshipResourceKeys, relationshipGrantees); err != nil {
+		t.Fatal(err)
+	}
+
+	var stale legacyResource
+	stale.Resource = makeResource("posts::1")
+	stale.Groups.Set("users", ActionRead)
+	if _, err = legacy.New(&stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = legacy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err = New(opts); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if g, err = groups.New(opts); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	p.SetGroups(g)
+
+	if _, err = g.AddGroups(testUser1, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	if ids, _, err = p.ListAccessible(testUser1, "posts", ActionRead, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || ids[0] != "1" {
+		t.Fatalf("invalid IDs, expected the legacy resource to be listed and received %v", ids)
+	}
+}
+
+// legacyResource is a resource as persisted prior to the resource ID within the grantees index
+type legacyResource struct {
+	Resource
+}
+
+func (l *legacyResource) GetRelationships() (rs mojura.Relationships) {
+	var keys []string
+	for _, group := range l.GetGrantees() {
+		keys = append(keys, group+granteeIndexSeparator+"posts")
+	}
+
+	rs.Append(l.Key)
+	rs.Append(keys...)
+	return
+}
+
+func TestPermissions_deny(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "staff"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = g.AddGroups(testUser2, "staff", "contractors"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("project::42", "staff", ActionRead|ActionWrite); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetDenyPermissions("project::42", "contractors", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("project::42/doc::7", "contractors", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Can(testUser1, "project::42", ActionRead) {
+		t.Fatal(testErrCannot)
+	}
+
+	if p.Can(testUser2, "project::42", ActionRead) {
+		t.Fatal(testErrCan)
+	}
+
+	if !p.Can(testUser2, "project::42", ActionWrite) {
+		// Only the denied actions are removed
+		t.Fatal(testErrCannot)
+	}
+
+	if p.Can(testUser2, "project::42/doc::7", ActionRead) {
+		// Inherited denies beat exact grants
+		t.Fatal(testErrCan)
+	}
+
+	d := p.Explain(testUser2, "project::42/doc::7", ActionRead)
+	if d.Reason != ReasonActionDenied || d.Grant == nil || !d.Grant.Deny || d.Grant.Group != "contractors" {
+		t.Fatalf("invalid decision, expected a deny for contractors and received %+v", d)
+	}
+
+	var ids []string
+	if ids, _, err = p.ListAccessible(testUser2, "doc", ActionRead, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 {
+		t.Fatalf("invalid accessible docs, expected none and received %v", ids)
+	}
+
+	if err = p.UnsetDenyPermissions("project::42", "contractors"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Can(testUser2, "project::42/doc::7", ActionRead) {
+		t.Fatal(testErrCannot)
+	}
+
+	// Setting permissions replaces the current actions
+	if err = p.SetPermissions("project::42", "staff", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Can(testUser1, "project::42", ActionWrite) {
+		t.Fatal(testErrCan)
+	}
+}
+
+func TestPermissions_RemoveGroup(t *testing.T) {
+	var err error
+	p, g := newTestPermissions(t)
+
+	if _, err = g.AddGroups(testUser1, "deployers"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("posts", "deployers", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetRolePermissions("project::42/doc::7", "deployers", RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetDenyPermissions("project::42", "deployers", ActionDelete); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.SetPermissions("posts", "staff", ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.RemoveGroup("deployers"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, resourceKey := range []string{"posts", "project::42", "project::42/doc::7"} {
+		var grants []Grant
+		if grants, err = p.ListGrantees(resourceKey); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, grant := range grants {
+			if grant.Group == "deployers" {
+				t.Fatalf("invalid grants for %s, expected deployers to be removed and received %+v", resourceKey, grant)
+			}
+		}
+	}
+
+	if p.Can(testUser1, "posts", ActionRead) {
+		t.Fatal(testErrCan)
+	}
+
+	var ids []string
+	if ids, _, err = p.ListAccessible(testUser1, "doc", ActionRead, ""); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 {
+		t.Fatalf("invalid accessible docs, expected none and received %v", ids)
+	}
+
+	// Grants held by other groups remain
+	if _, err = g.AddGroups(testUser2, "staff"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Can(testUser2, "posts", ActionRead) {
+		t.Fatal(testErrCannot)
+	}
+}
+
 func testPerms(p *Permissions, t *testing.T) {
 	if !p.Can(testUser1, "posts", ActionRead) {
 		t.Fatal(testErrCannot)
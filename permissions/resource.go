@@ -1,6 +1,8 @@
 package permissions
 
 import (
+	"sort"
+
 	"github.com/mojura/mojura"
 )
 
@@ -16,6 +18,84 @@ type Resource struct {
 
 	Key    string `json:"key"`
 	Groups `json:"groups"`
+	// Roles are the role names assigned to groups
+	Roles map[string]string `json:"roles,omitempty"`
+	// Denies are the actions denied to groups, denies take priority over grants
+	Denies Groups `json:"denies,omitempty"`
+}
+
+// HasGrant will return whether or not a group has actions or a role for the resource
+func (r *Resource) HasGrant(group string) bool {
+	if r.Has(group) {
+		return true
+	}
+
+	_, ok := r.Roles[group]
+	return ok
+}
+
+// GetRole will get the role assigned to a group
+func (r *Resource) GetRole(group string) (role string, ok bool) {
+	role, ok = r.Roles[group]
+	return
+}
+
+// SetRole will assign a role to a group
+func (r *Resource) SetRole(group, role string) (ok bool) {
+	if current, ok := r.Roles[group]; ok && current == role {
+		return false
+	}
+
+	if r.Roles == nil {
+		r.Roles = make(map[string]string)
+	}
+
+	r.Roles[group] = role
+	return true
+}
+
+// RemoveRole will remove the role assigned to a group
+func (r *Resource) RemoveRole(group string) (ok bool) {
+	if _, ok = r.Roles[group]; ok {
+		delete(r.Roles, group)
+	}
+
+	return
+}
+
+// HasDeny will return whether or not a group has denied actions for the resource
+func (r *Resource) HasDeny(group string) bool {
+	return r.Denies.Has(group)
+}
+
+// SetDeny will set the actions denied to a group, replacing any currently denied actions
+func (r *Resource) SetDeny(group string, actions Action) (ok bool) {
+	if r.Denies == nil {
+		r.Denies = make(Groups)
+	}
+
+	return r.Denies.Set(group, actions)
+}
+
+// RemoveDeny will remove the actions denied to a group
+func (r *Resource) RemoveDeny(group string) (ok bool) {
+	return r.Denies.Remove(group)
+}
+
+// GetGrantees will return the groups which have actions or a role for the resource, sorted by name
+func (r *Resource) GetGrantees() (groups []string) {
+	for group := range r.Groups {
+		groups = append(groups, group)
+	}
+
+	for group := range r.Roles {
+		if !r.Has(group) {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Strings(groups)
+	return
 }
 
 // mojura.Value interface methods below
@@ -32,6 +112,22 @@ func (r *Resource) GetUpdatedAt() (updatedAt int64) { return r.UpdatedAt }
 // GetRelationships will get the associated relationship IDs
 func (r *Resource) GetRelationships() (rs mojura.Relationships) {
 	rs.Append(r.Key)
+	rs.Append(r.getGranteeIndexKeys()...)
+	return
+}
+
+// getGranteeIndexKeys will return the group, resource name and resource ID keys used to list accessible resources
+// Note: Only resources with an ID are indexed, see ListAccessible
+func (r *Resource) getGranteeIndexKeys() (keys []string) {
+	resourceName, resourceID := splitResourceKey(r.Key)
+	if len(resourceID) == 0 {
+		return
+	}
+
+	for _, group := range r.GetGrantees() {
+		keys = append(keys, newGranteeIndexKey(group, resourceName, resourceID))
+	}
+
 	return
 }
 
@@ -0,0 +1,33 @@
+package permissions
+
+import "github.com/mojura/mojura"
+
+func makeRole(name string, actions Action) (r Role) {
+	r.Name = name
+	r.Actions = actions
+	return
+}
+
+// Role represents a named set of actions
+// Note: Resources reference roles by name, the actions are resolved at check time
+type Role struct {
+	mojura.Entry
+
+	Name    string `json:"name"`
+	Actions Action `json:"actions"`
+}
+
+// Validate will validate a role
+func (r *Role) Validate() (err error) {
+	if len(r.Name) == 0 {
+		return ErrInvalidRoleName
+	}
+
+	return
+}
+
+// GetRelationships will get the associated relationship IDs
+func (r *Role) GetRelationships() (rs mojura.Relationships) {
+	rs.Append(r.Name)
+	return
+}
@@ -0,0 +1,175 @@
+package permissions
+
+import (
+	"context"
+
+	"github.com/mojura/mojura"
+	"github.com/mojura/mojura/filters"
+)
+
+const (
+	// RoleViewer is the default role for reading
+	RoleViewer = "viewer"
+	// RoleEditor is the default role for reading and writing
+	RoleEditor = "editor"
+	// RoleOwner is the default role for reading, writing and deleting
+	RoleOwner = "owner"
+)
+
+const (
+	relationshipRoleNames = "roleNames"
+)
+
+// DefaultRoles are the roles created when they do not already exist
+var DefaultRoles = map[string]Action{
+	RoleViewer: ActionRead,
+	RoleEditor: ActionRead | ActionWrite,
+	RoleOwner:  ActionRead | ActionWrite | ActionDelete,
+}
+
+// SetRole will create or redefine a role
+// Note: Redefining a role changes access for every resource which references it
+func (p *Permissions) SetRole(name string, actions Action) (err error) {
+	role := makeRole(name, actions)
+	if err = role.Validate(); err != nil {
+		return
+	}
+
+	err = p.r.Transaction(context.Background(), func(txn *mojura.Transaction[*Role]) (err error) {
+		return p.setRole(txn, role)
+	})
+
+	p.roles.invalidate(name)
+	return
+}
+
+// GetRole will get a role by name
+func (p *Permissions) GetRole(name string) (role *Role, err error) {
+	err = p.r.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Role]) (err error) {
+		role, err = p.getRole(txn, name)
+		return
+	})
+
+	return
+}
+
+// GetRoles will get all roles
+func (p *Permissions) GetRoles() (roles []*Role, err error) {
+	err = p.r.ForEach(func(_ string, role *Role) (err error) {
+		roles = append(roles, role)
+		return
+	}, nil)
+
+	return
+}
+
+// RemoveRole will remove a role by name
+// Note: Resources which reference a removed role grant nothing through it
+func (p *Permissions) RemoveRole(name string) (err error) {
+	err = p.r.Transaction(context.Background(), func(txn *mojura.Transaction[*Role]) (err error) {
+		var role *Role
+		if role, err = p.getRole(txn, name); err != nil {
+			return
+		}
+
+		_, err = txn.Delete(role.ID)
+		return
+	})
+
+	p.roles.invalidate(name)
+	return
+}
+
+// SetRolePermissions will assign a role to a group for a resource key
+func (p *Permissions) SetRolePermissions(resourceKey, group, role string) (err error) {
+	if _, err = p.GetRole(role); err != nil {
+		return
+	}
+
+	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		return p.setRolePermissions(txn, resourceKey, group, role)
+	})
+
+	p.resources.invalidate(resourceKey)
+	return
+}
+
+// UnsetRolePermissions will remove the role assigned to a group for a resource key
+func (p *Permissions) UnsetRolePermissions(resourceKey, group string) (err error) {
+	err = p.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Resource]) (err error) {
+		return p.unsetRolePermissions(txn, resourceKey, group)
+	})
+
+	p.resources.invalidate(resourceKey)
+	return
+}
+
+func (p *Permissions) setDefaultRoles() (err error) {
+	err = p.r.Transaction(context.Background(), func(txn *mojura.Transaction[*Role]) (err error) {
+		for name, actions := range DefaultRoles {
+			if _, err = p.getRole(txn, name); err == nil {
+				// Role has already been defined, do not overwrite
+				continue
+			}
+
+			if err = p.setRole(txn, makeRole(name, actions)); err != nil {
+				return
+			}
+		}
+
+		return
+	})
+
+	return
+}
+
+func (p *Permissions) setRole(txn *mojura.Transaction[*Role], role Role) (err error) {
+	var match *Role
+	switch match, err = p.getRole(txn, role.Name); err {
+	case nil:
+		match.Actions = role.Actions
+		_, err = txn.Put(match.ID, match)
+	case ErrRoleNotFound:
+		_, err = txn.New(&role)
+	}
+
+	return
+}
+
+func (p *Permissions) getRole(txn *mojura.Transaction[*Role], name string) (role *Role, err error) {
+	filter := filters.Match(relationshipRoleNames, name)
+	opts := mojura.NewFilteringOpts(filter)
+	if role, err = txn.GetFirst(opts); err == mojura.ErrEntryNotFound {
+		err = ErrRoleNotFound
+	}
+
+	return
+}
+
+func (p *Permissions) setRolePermissions(txn *mojura.Transaction[*Resource], resourceKey, group, role string) (err error) {
+	var r *Resource
+	if r, err = p.getOrCreateByKey(txn, resourceKey); err != nil {
+		return
+	}
+
+	if !r.SetRole(group, role) {
+		return
+	}
+
+	_, err = txn.Put(r.ID, r)
+	return
+}
+
+func (p *Permissions) unsetRolePermissions(txn *mojura.Transaction[*Resource], resourceKey, group string) (err error) {
+	var r *Resource
+	if r, err = p.getByKey(txn, resourceKey); err != nil {
+		return
+	}
+
+	if !r.RemoveRole(group) {
+		return
+	}
+
+	_, err = txn.Put(r.ID, r)
+	return
+}
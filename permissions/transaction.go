@@ -13,6 +13,9 @@ func newTransaction(txn *mojura.Transaction[*Resource], p *Permissions) (t Trans
 type Transaction struct {
 	txn *mojura.Transaction[*Resource]
 	p   *Permissions
+
+	// touched are the resource keys written to, they are invalidated within the cache after commit
+	touched []string
 }
 
 // Get will get the resource entry for a given resource ID
@@ -35,11 +38,13 @@ func (t *Transaction) ForEach(fn func(*Resource) error, opts *mojura.FilteringOp
 
 // SetPermissions will set the permissions for a resource key being accessed by given group
 func (t *Transaction) SetPermissions(resourceKey, group string, actions Action) (err error) {
+	t.touched = append(t.touched, resourceKey)
 	return t.p.setPermissions(t.txn, resourceKey, group, actions)
 }
 
 // SetMultiPermissions will set the permissions for a resource key being accessed by given group
 func (t *Transaction) SetMultiPermissions(resourceKey string, pairs ...Pair) (err error) {
+	t.touched = append(t.touched, resourceKey)
 	for _, pair := range pairs {
 		if err = t.p.setPermissions(t.txn, resourceKey, pair.Group, pair.Actions); err != nil {
 			return
@@ -51,9 +56,35 @@ func (t *Transaction) SetMultiPermissions(resourceKey string, pairs ...Pair) (er
 
 // UnsetPermissions will remove the permissions for a resource key being accessed by given group
 func (t *Transaction) UnsetPermissions(resourceKey, group string) (err error) {
+	t.touched = append(t.touched, resourceKey)
 	return t.p.unsetPermissions(t.txn, resourceKey, group)
 }
 
+// SetDenyPermissions will set the actions denied to a given group for a resource key
+func (t *Transaction) SetDenyPermissions(resourceKey, group string, actions Action) (err error) {
+	t.touched = append(t.touched, resourceKey)
+	return t.p.setDenyPermissions(t.txn, resourceKey, group, actions)
+}
+
+// UnsetDenyPermissions will remove the actions denied to a given group for a resource key
+func (t *Transaction) UnsetDenyPermissions(resourceKey, group string) (err error) {
+	t.touched = append(t.touched, resourceKey)
+	return t.p.unsetDenyPermissions(t.txn, resourceKey, group)
+}
+
+// SetRolePermissions will assign a role to a group for a resource key
+// Note: The role is not verified to exist, see Permissions.SetRolePermissions
+func (t *Transaction) SetRolePermissions(resourceKey, group, role string) (err error) {
+	t.touched = append(t.touched, resourceKey)
+	return t.p.setRolePermissions(t.txn, resourceKey, group, role)
+}
+
+// UnsetRolePermissions will remove the role assigned to a group for a resource key
+func (t *Transaction) UnsetRolePermissions(resourceKey, group string) (err error) {
+	t.touched = append(t.touched, resourceKey)
+	return t.p.unsetRolePermissions(t.txn, resourceKey, group)
+}
+
 // Can will return if a user (userID) can perform a given action on a provided resource id
 // Note: This isn't done as a transaction because it's two GET requests which don't need to block
 func (t *Transaction) Can(userID, resourceKey string, action Action) (can bool) {
@@ -72,5 +103,13 @@ func (t *Transaction) Groups(userID string) (groups []string, err error) {
 
 // RemoveResource will remove a resource by key
 func (t *Transaction) RemoveResource(resourceKey string) (err error) {
+	t.touched = append(t.touched, resourceKey)
 	return t.p.removeResource(t.txn, resourceKey)
 }
+
+// Explain will return the decision of whether a user (userID) can perform a given action on a provided resource key
+func (t *Transaction) Explain(userID, resourceKey string, action Action) (d Decision) {
+	l := t.p.newLookup(false)
+	l.txn = t.txn
+	return t.p.explain(&l, userID, resourceKey, action)
+}
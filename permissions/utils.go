@@ -1 +1,79 @@
 package permissions
+
+import "strings"
+
+const (
+	// KeySeparator separates the segments of a hierarchical resource key (e.g. project::42/doc::7)
+	KeySeparator = "/"
+	// Wildcard is the final segment of a resource key which grants access to all descendants (e.g. project::42/*)
+	Wildcard = "*"
+)
+
+const (
+	// idSeparator separates the resource name and ID of a resource key segment (e.g. doc::7)
+	idSeparator = "::"
+	// granteeIndexSeparator separates the group, resource name and resource ID of a grantee index key
+	granteeIndexSeparator = "|"
+)
+
+var (
+	// resourceIDEscaper escapes the characters of a resource ID which are reserved within resource keys
+	// Note: The escape character is escaped first so escaped IDs remain unique
+	resourceIDEscaper   = strings.NewReplacer("%", "%25", KeySeparator, "%2F", Wildcard, "%2A")
+	resourceIDUnescaper = strings.NewReplacer("%25", "%", "%2F", KeySeparator, "%2A", Wildcard)
+)
+
+// EscapeResourceID will escape the KeySeparator and Wildcard characters of a resource ID
+// An escaped ID is a single segment of a resource key, so it cannot inherit the grants of a made-up parent
+// (e.g. a grant on files::alice does not extend to the ID alice/secret)
+func EscapeResourceID(resourceID string) string {
+	return resourceIDEscaper.Replace(resourceID)
+}
+
+// UnescapeResourceID will reverse EscapeResourceID
+func UnescapeResourceID(resourceID string) string {
+	return resourceIDUnescaper.Replace(resourceID)
+}
+
+// getCandidateKeys will return the resource keys which may grant access to a resource key, most specific first
+// For project::42/doc::7 the candidates are:
+//   - project::42/doc::7
+//   - project::42/*
+//   - project::42
+func getCandidateKeys(resourceKey string) (keys []string) {
+	keys = append(keys, resourceKey)
+	parent := resourceKey
+	for {
+		index := strings.LastIndex(parent, KeySeparator)
+		if index == -1 {
+			break
+		}
+
+		parent = parent[:index]
+		keys = append(keys, parent+KeySeparator+Wildcard, parent)
+	}
+
+	return
+}
+
+// splitResourceKey will return the resource name and ID of the final segment of a resource key
+// For project::42/doc::7 the resource name is doc and the resource ID is 7
+func splitResourceKey(resourceKey string) (resourceName, resourceID string) {
+	segment := resourceKey
+	if index := strings.LastIndex(segment, KeySeparator); index > -1 {
+		segment = segment[index+len(KeySeparator):]
+	}
+
+	resourceName, resourceID, _ = strings.Cut(segment, idSeparator)
+	return
+}
+
+// newGranteeIndexKey will return the grantee index key of a resource, keys sort by resource ID within a group and resource name
+func newGranteeIndexKey(group, resourceName, resourceID string) string {
+	return newGranteeIndexPrefix(group, resourceName) + resourceID
+}
+
+// newGranteeIndexPrefix will return the prefix shared by the grantee index keys of a group and resource name
+func newGranteeIndexPrefix(group, resourceName string) string {
+	return group + granteeIndexSeparator + resourceName + granteeIndexSeparator
+}
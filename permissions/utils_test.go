@@ -0,0 +1,33 @@
+package permissions
+
+import "testing"
+
+func TestEscapeResourceID(t *testing.T) {
+	type testcase struct {
+		resourceID string
+		expected   string
+	}
+
+	tcs := []testcase{
+		{resourceID: "alice", expected: "alice"},
+		{resourceID: "alice/secret", expected: "alice%2Fsecret"},
+		{resourceID: "*", expected: "%2A"},
+		{resourceID: "100%", expected: "100%25"},
+		{resourceID: "%2F", expected: "%252F"},
+	}
+
+	for _, tc := range tcs {
+		escaped := EscapeResourceID(tc.resourceID)
+		if escaped != tc.expected {
+			t.Fatalf("invalid escaped ID for <%s>, expected <%s> and received <%s>", tc.resourceID, tc.expected, escaped)
+		}
+
+		if unescaped := UnescapeResourceID(escaped); unescaped != tc.resourceID {
+			t.Fatalf("invalid unescaped ID for <%s>, expected <%s> and received <%s>", escaped, tc.resourceID, unescaped)
+		}
+
+		if keys := getCandidateKeys("files::" + escaped); len(keys) != 1 {
+			t.Fatalf("invalid candidate keys for <%s>, expected a single segment and received %v", tc.resourceID, keys)
+		}
+	}
+}
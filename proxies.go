@@ -0,0 +1,87 @@
+package jump
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gdbu/jump/apikeys"
+)
+
+// IPRejection is the value of an api-key-ip-rejected event
+type IPRejection struct {
+	// KeyID is the sanitized identifier of the key, see apikeys.APIKey.SanitizedID
+	KeyID  string `json:"keyID"`
+	UserID string `json:"userID"`
+	IP     string `json:"ip"`
+}
+
+func makeIPRejection(a *apikeys.APIKey, ip string) (r IPRejection) {
+	r.KeyID = a.SanitizedID()
+	r.UserID = a.UserID
+	r.IP = ip
+	return
+}
+
+// SetTrustedProxies will set the CIDR ranges of the proxies permitted to set X-Forwarded-For
+// Note: When no proxies are trusted, X-Forwarded-For is ignored and the peer address is used
+func (j *Jump) SetTrustedProxies(cidrs []string) (err error) {
+	proxies := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		var ipNet *net.IPNet
+		if _, ipNet, err = net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("error parsing trusted proxy <%s>: %v", cidr, err)
+		}
+
+		proxies = append(proxies, ipNet)
+	}
+
+	j.proxyMux.Lock()
+	defer j.proxyMux.Unlock()
+	j.trustedProxies = proxies
+	return
+}
+
+// getClientIP will return the IP address of the client which made the request
+// When the peer is a trusted proxy, X-Forwarded-For is walked from right to left and the first
+// address which is not a trusted proxy is returned. Addresses left of it are client controlled.
+func (j *Jump) getClientIP(req *http.Request) (ip string) {
+	ip = getRemoteIP(req)
+	if !j.isTrustedProxy(ip) {
+		return
+	}
+
+	forwarded := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			// Malformed entry, the chain cannot be trusted beyond this point
+			return
+		}
+
+		ip = hop
+		if !j.isTrustedProxy(hop) {
+			return
+		}
+	}
+
+	return
+}
+
+func (j *Jump) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	j.proxyMux.RLock()
+	defer j.proxyMux.RUnlock()
+	for _, proxy := range j.trustedProxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
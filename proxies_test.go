@@ -0,0 +1,67 @@
+package jump
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gdbu/jump/apikeys"
+)
+
+func TestJump_getClientIP(t *testing.T) {
+	type testcase struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}
+
+	tcs := []testcase{
+		{name: "no proxies trusted", remoteAddr: "203.0.113.7:4000", forwarded: []string{"198.51.100.1"}, expected: "203.0.113.7"},
+		{name: "untrusted peer", proxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:4000", forwarded: []string{"198.51.100.1"}, expected: "203.0.113.7"},
+		{name: "remote address without port", proxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7", expected: "203.0.113.7"},
+		{name: "trusted peer", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "trusted peer without header", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", expected: "10.0.0.1"},
+		{name: "spoofed", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"192.0.2.1, 198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "multi-hop", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"198.51.100.1, 10.0.0.2, 10.0.0.3"}, expected: "198.51.100.1"},
+		{name: "multi-hop across headers", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"192.0.2.1, 198.51.100.1", "10.0.0.2"}, expected: "198.51.100.1"},
+		{name: "all hops trusted", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"10.0.0.2, 10.0.0.3"}, expected: "10.0.0.2"},
+		{name: "malformed hop", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"198.51.100.1, not-an-ip"}, expected: "10.0.0.1"},
+		{name: "malformed hop beyond client", proxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:4000", forwarded: []string{"not-an-ip, 198.51.100.1"}, expected: "198.51.100.1"},
+	}
+
+	j := newTestJump(t)
+	for _, tc := range tcs {
+		if err := j.SetTrustedProxies(tc.proxies); err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.RemoteAddr = tc.remoteAddr
+		for _, value := range tc.forwarded {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+
+		if ip := j.getClientIP(req); ip != tc.expected {
+			t.Fatalf("invalid IP for <%s>, expected <%s> and received <%s>", tc.name, tc.expected, ip)
+		}
+	}
+}
+
+func Test_makeIPRejection(t *testing.T) {
+	legacy := apikeys.APIKey{Key: "0123456789abcdef0123456789abcdef", UserID: testUser1}
+	legacy.ID = "00000001"
+	if r := makeIPRejection(&legacy, "203.0.113.7"); r.KeyID != legacy.ID {
+		t.Fatalf("invalid key ID, expected <%s> and received <%s>", legacy.ID, r.KeyID)
+	}
+
+	prefixed := apikeys.APIKey{Key: "key_id", SecretHash: "hash", UserID: testUser1}
+	prefixed.ID = "00000002"
+	if r := makeIPRejection(&prefixed, "203.0.113.7"); r.KeyID != prefixed.Key {
+		t.Fatalf("invalid key ID, expected <%s> and received <%s>", prefixed.Key, r.KeyID)
+	}
+}
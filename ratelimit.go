@@ -0,0 +1,133 @@
+package jump
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/ratelimit"
+)
+
+// NewRateLimitMW will throttle requests to a route using a token bucket per principal
+// The principal is the API key used to authenticate the request, falling back to the user ID and then the client IP.
+// The applied limit is the API key limit (if set), otherwise the stricter of the provided route limit and the most permissive
+// group limit (if set). Group limits can tighten a route, they cannot loosen it.
+// Note: This middleware must be placed after NewSetUserIDMW to throttle by API key or user ID
+func (j *Jump) NewRateLimitMW(route string, l ratelimit.Limit) httpserve.Handler {
+	return func(ctx *httpserve.Context) {
+		principal := j.getPrincipal(ctx)
+		limit := j.getRateLimit(ctx, l)
+		if limit.IsUnlimited() {
+			return
+		}
+
+		r, err := j.getLimiter().Take(route+":"+principal, limit, time.Now())
+		if err != nil {
+			// Fail open, an unavailable limiter store should not take the service down
+			j.out.Error(fmt.Sprintf("error taking rate limit token for %s: %v", principal, err))
+			return
+		}
+
+		hdr := ctx.Writer().Header()
+		hdr.Set("RateLimit-Limit", strconv.Itoa(r.Limit))
+		hdr.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+		hdr.Set("RateLimit-Reset", formatSeconds(r.Reset))
+		if r.Allowed {
+			return
+		}
+
+		hdr.Set("Retry-After", formatSeconds(r.RetryAfter))
+		ctx.WriteJSON(429, ErrRateLimitExceeded)
+	}
+}
+
+// SetRateLimitStore will set the store used to manage limiter state, the default is an in-memory store
+func (j *Jump) SetRateLimitStore(s ratelimit.Store) {
+	j.rateMux.Lock()
+	defer j.rateMux.Unlock()
+	j.limiter = s
+}
+
+// SetAPIKeyRateLimit will set the rate limit for requests authenticated by an API key (by key ID)
+func (j *Jump) SetAPIKeyRateLimit(keyID string, l ratelimit.Limit) {
+	j.rateMux.Lock()
+	defer j.rateMux.Unlock()
+	j.keyRateLimits[keyID] = l
+}
+
+// SetGroupRateLimit will set the rate limit for members of a group
+// Note: When a user belongs to multiple limited groups, the most permissive limit applies. Group limits
+// only apply where they are stricter than the route limit, see NewRateLimitMW
+func (j *Jump) SetGroupRateLimit(group string, l ratelimit.Limit) {
+	j.rateMux.Lock()
+	defer j.rateMux.Unlock()
+	j.groupRateLimits[group] = l
+}
+
+func (j *Jump) getLimiter() ratelimit.Store {
+	j.rateMux.RLock()
+	defer j.rateMux.RUnlock()
+	return j.limiter
+}
+
+func (j *Jump) getRateLimit(ctx *httpserve.Context, routeLimit ratelimit.Limit) (l ratelimit.Limit) {
+	j.rateMux.RLock()
+	defer j.rateMux.RUnlock()
+	if kl, ok := j.keyRateLimits[ctx.Get("apiKeyID")]; ok {
+		return kl
+	}
+
+	userID := ctx.Get("userID")
+	if len(userID) == 0 || len(j.groupRateLimits) == 0 {
+		return routeLimit
+	}
+
+	groups, err := j.grps.Get(userID)
+	if err != nil {
+		j.out.Error(fmt.Sprintf("error getting groups for rate limit of %s: %v", userID, err))
+		return routeLimit
+	}
+
+	var matched bool
+	for _, group := range groups {
+		gl, ok := j.groupRateLimits[group]
+		switch {
+		case !ok:
+		case !matched:
+			l = gl
+			matched = true
+		case gl.IsUnlimited():
+			// An unlimited group cannot loosen the route limit
+			return routeLimit
+		case !l.IsUnlimited() && gl.Rate > l.Rate:
+			l = gl
+		}
+	}
+
+	if !matched {
+		return routeLimit
+	}
+
+	return routeLimit.Min(l)
+}
+
+// getPrincipal will return the rate limiting principal of the request
+func (j *Jump) getPrincipal(ctx *httpserve.Context) (principal string) {
+	if keyID := ctx.Get("apiKeyID"); len(keyID) > 0 {
+		return "apiKey:" + keyID
+	}
+
+	if userID := ctx.Get("userID"); len(userID) > 0 {
+		return "user:" + userID
+	}
+
+	return "ip:" + j.getClientIP(ctx.Request())
+}
+
+// formatSeconds will format a duration as whole seconds, rounded up
+func formatSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// NewLimit will return a limit allowing the provided number of requests per interval
+// Note: The burst is set to the number of requests, allowing the full allotment at once
+func NewLimit(requests int, per time.Duration) (l Limit) {
+	if requests <= 0 || per <= 0 {
+		return
+	}
+
+	l.Rate = float64(requests) / per.Seconds()
+	l.Burst = requests
+	return
+}
+
+// Limit represents a token bucket limit
+type Limit struct {
+	// Rate is the number of tokens refilled per second
+	Rate float64 `json:"rate" toml:"rate"`
+	// Burst is the maximum number of tokens the bucket can hold
+	Burst int `json:"burst" toml:"burst"`
+}
+
+// IsUnlimited will return whether or not the limit is unrestricted
+func (l Limit) IsUnlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Min will return the stricter of two limits, taking the lower rate and the lower burst
+// Note: An unlimited limit never loosens the other limit
+func (l Limit) Min(other Limit) Limit {
+	switch {
+	case l.IsUnlimited():
+		return other
+	case other.IsUnlimited():
+		return l
+	}
+
+	return Limit{Rate: math.Min(l.Rate, other.Rate), Burst: min(l.Burst, other.Burst)}
+}
+
+// refillDuration will return the duration needed to refill the provided number of tokens
+func (l Limit) refillDuration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+
+	return time.Duration(tokens / l.Rate * float64(time.Second))
+}
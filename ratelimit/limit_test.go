@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimit_Min(t *testing.T) {
+	type testcase struct {
+		name     string
+		a        Limit
+		b        Limit
+		expected Limit
+	}
+
+	tcs := []testcase{
+		{name: "stricter first", a: NewLimit(10, time.Second), b: NewLimit(100, time.Second), expected: NewLimit(10, time.Second)},
+		{name: "stricter second", a: NewLimit(100, time.Second), b: NewLimit(10, time.Second), expected: NewLimit(10, time.Second)},
+		{name: "mixed", a: Limit{Rate: 1, Burst: 50}, b: Limit{Rate: 5, Burst: 10}, expected: Limit{Rate: 1, Burst: 10}},
+		{name: "unlimited first", a: Limit{}, b: NewLimit(10, time.Second), expected: NewLimit(10, time.Second)},
+		{name: "unlimited second", a: NewLimit(10, time.Second), b: Limit{}, expected: NewLimit(10, time.Second)},
+		{name: "both unlimited", a: Limit{}, b: Limit{}, expected: Limit{}},
+	}
+
+	for _, tc := range tcs {
+		if l := tc.a.Min(tc.b); l != tc.expected {
+			t.Fatalf("invalid limit for <%s>, expected <%+v> and received <%+v>", tc.name, tc.expected, l)
+		}
+	}
+}
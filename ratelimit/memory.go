@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// CleanupInterval is the minimum interval between scans for idle buckets
+const CleanupInterval = time.Minute
+
+// NewMemory will return a new in-memory limiter store
+func NewMemory() *Memory {
+	var m Memory
+	m.buckets = make(map[string]*bucket)
+	return &m
+}
+
+// Memory is an in-memory limiter store
+// Note: State is local to the process, use a shared Store when running multiple instances
+type Memory struct {
+	mux     sync.Mutex
+	buckets map[string]*bucket
+
+	lastCleanup time.Time
+}
+
+// Take will attempt to take a token for the provided key
+func (m *Memory) Take(key string, l Limit, now time.Time) (r Result, err error) {
+	r.Limit = l.Burst
+	if l.IsUnlimited() {
+		r.Allowed = true
+		return
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.cleanup(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), updatedAt: now}
+		m.buckets[key] = b
+	}
+
+	b.refill(l, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = l.refillDuration(1 - b.tokens)
+	}
+
+	r.Remaining = int(math.Floor(b.tokens))
+	r.Reset = l.refillDuration(float64(l.Burst) - b.tokens)
+	return
+}
+
+// cleanup will remove the buckets which have been idle long enough to be full
+func (m *Memory) cleanup(now time.Time) {
+	if now.Sub(m.lastCleanup) < CleanupInterval {
+		return
+	}
+
+	m.lastCleanup = now
+	for key, b := range m.buckets {
+		if now.After(b.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+func (b *bucket) refill(l Limit, now time.Time) {
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = math.Min(float64(l.Burst), b.tokens+elapsed.Seconds()*l.Rate)
+		b.updatedAt = now
+	}
+
+	b.fullAt = now.Add(l.refillDuration(float64(l.Burst) - b.tokens + 1))
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory_Take(t *testing.T) {
+	var (
+		r   Result
+		err error
+	)
+
+	m := NewMemory()
+	l := NewLimit(2, time.Second)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if r, err = m.Take("test", l, now); err != nil {
+			t.Fatal(err)
+		} else if !r.Allowed {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+
+	if r, err = m.Take("test", l, now); err != nil {
+		t.Fatal(err)
+	} else if r.Allowed {
+		t.Fatal("expected request to be rejected")
+	} else if r.RetryAfter != time.Millisecond*500 {
+		t.Fatalf("invalid retry after, expected <%v> and received <%v>", time.Millisecond*500, r.RetryAfter)
+	}
+
+	if r, err = m.Take("other", l, now); err != nil {
+		t.Fatal(err)
+	} else if !r.Allowed {
+		t.Fatal("expected request for other key to be allowed")
+	}
+
+	if r, err = m.Take("test", l, now.Add(time.Millisecond*500)); err != nil {
+		t.Fatal(err)
+	} else if !r.Allowed {
+		t.Fatal("expected request to be allowed after refill")
+	}
+}
+
+func TestMemory_cleanup(t *testing.T) {
+	m := NewMemory()
+	l := NewLimit(1, time.Second)
+	now := time.Now()
+	if _, err := m.Take("test", l, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Take("other", l, now.Add(CleanupInterval+time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m.buckets["test"]; ok {
+		t.Fatal("expected idle bucket to be removed")
+	}
+}
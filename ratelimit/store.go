@@ -0,0 +1,24 @@
+package ratelimit
+
+import "time"
+
+// Store manages limiter state
+// Note: Implementations must be safe for concurrent use
+type Store interface {
+	// Take will attempt to take a token for the provided key
+	Take(key string, l Limit, now time.Time) (Result, error)
+}
+
+// Result represents the outcome of a token request
+type Result struct {
+	// Allowed is whether or not a token was taken
+	Allowed bool
+	// Limit is the burst size of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// RetryAfter is the duration until the next token is available, zero when allowed
+	RetryAfter time.Duration
+	// Reset is the duration until the bucket is full
+	Reset time.Duration
+}
@@ -0,0 +1,75 @@
+package jump
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/ratelimit"
+)
+
+func TestJump_NewRateLimitMW_groups(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) (err error) {
+		handler := func(ctx *httpserve.Context) {
+			ctx.WriteNoContent()
+		}
+
+		if err = s.GET("/strict", j.NewSetUserIDMW(false, false), j.NewRateLimitMW("strict", ratelimit.NewLimit(1, time.Hour)), handler); err != nil {
+			return
+		}
+
+		return s.GET("/loose", j.NewSetUserIDMW(false, false), j.NewRateLimitMW("loose", ratelimit.NewLimit(100, time.Hour)), handler)
+	})
+
+	if _, err := j.grps.AddGroups(testUser1, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	j.SetGroupRateLimit("users", ratelimit.NewLimit(2, time.Hour))
+
+	key, token, err := j.sess.New(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (statusCode int) {
+		req, err := http.NewRequest("GET", baseURL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.AddCookie(&http.Cookie{Name: CookieKey, Value: key})
+		req.AddCookie(&http.Cookie{Name: CookieToken, Value: token})
+
+		var res *http.Response
+		if res, err = http.DefaultClient.Do(req); err != nil {
+			t.Fatal(err)
+		}
+
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// The group limit cannot loosen the stricter route limit
+	if statusCode := get("/strict"); statusCode != 204 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 204, statusCode)
+	}
+
+	if statusCode := get("/strict"); statusCode != 429 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 429, statusCode)
+	}
+
+	// The group limit tightens the looser route limit
+	for i := 0; i < 2; i++ {
+		if statusCode := get("/loose"); statusCode != 204 {
+			t.Fatalf("invalid status code for request %d, expected <%d> and received <%d>", i, 204, statusCode)
+		}
+	}
+
+	if statusCode := get("/loose"); statusCode != 429 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 429, statusCode)
+	}
+}
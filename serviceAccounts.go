@@ -0,0 +1,164 @@
+package jump
+
+import (
+	"github.com/gdbu/jump/apikeys"
+	"github.com/gdbu/jump/serviceaccounts"
+	"github.com/gdbu/jump/users"
+)
+
+const (
+	// PrincipalTypeUser is the principal type of users
+	PrincipalTypeUser = "user"
+	// PrincipalTypeServiceAccount is the principal type of service accounts
+	PrincipalTypeServiceAccount = "serviceAccount"
+)
+
+// Principal represents a user or service account within a listing
+type Principal struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Name is the email of a user or the name of a service account
+	Name     string `json:"name"`
+	Disabled bool   `json:"disabled"`
+	// OwnerID is the user responsible for a service account, empty for users
+	OwnerID string `json:"ownerID,omitempty"`
+}
+
+// CreateServiceAccount will create a service account owned by a user and assign it's basic groups
+// Note: Service accounts cannot log in, the returned API key is their only means of authentication
+func (j *Jump) CreateServiceAccount(ownerID, name, description string, groups ...string) (principalID, apiKey string, err error) {
+	if _, err = j.usrs.Get(ownerID); err != nil {
+		return
+	}
+
+	var sa *serviceaccounts.ServiceAccount
+	if sa, err = j.svcs.New(ownerID, name, description); err != nil {
+		return
+	}
+
+	principalID = sa.PrincipalID()
+	apiKey, err = j.postServiceAccountCreateActions(principalID, ownerID, groups)
+	return
+}
+
+// GetServiceAccount will get a service account by principal ID
+func (j *Jump) GetServiceAccount(principalID string) (sa *serviceaccounts.ServiceAccount, err error) {
+	return j.svcs.Get(principalID)
+}
+
+// GetServiceAccountsByOwner will get the service accounts owned by a user
+func (j *Jump) GetServiceAccountsByOwner(ownerID string) (sas []*serviceaccounts.ServiceAccount, err error) {
+	return j.svcs.GetByOwner(ownerID)
+}
+
+// GetPrincipalsList will get the current users and service accounts, labeled by principal type
+func (j *Jump) GetPrincipalsList() (ps []Principal, err error) {
+	if err = j.usrs.ForEach(func(user *users.User) (err error) {
+		ps = append(ps, Principal{ID: user.ID, Type: PrincipalTypeUser, Name: user.Email, Disabled: user.Disabled})
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = j.svcs.ForEach(func(sa *serviceaccounts.ServiceAccount) (err error) {
+		p := Principal{ID: sa.PrincipalID(), Type: PrincipalTypeServiceAccount, Name: sa.Name, Disabled: sa.Disabled, OwnerID: sa.OwnerID}
+		ps = append(ps, p)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// TransferServiceAccount will transfer ownership of a service account to another user
+func (j *Jump) TransferServiceAccount(principalID, ownerID string) (err error) {
+	if _, err = j.usrs.Get(ownerID); err != nil {
+		return
+	}
+
+	var sa *serviceaccounts.ServiceAccount
+	if sa, err = j.svcs.Get(principalID); err != nil {
+		return
+	}
+
+	if _, err = j.svcs.UpdateOwner(principalID, ownerID); err != nil {
+		return
+	}
+
+	resourceKey := NewResourceKey("serviceAccount", principalID)
+	if err = j.UnsetPermission(resourceKey, sa.OwnerID); err != nil {
+		return
+	}
+
+	return j.SetPermission(resourceKey, ownerID, permRWD, permRWD)
+}
+
+// EnableServiceAccount will enable a service account
+func (j *Jump) EnableServiceAccount(principalID string) (err error) {
+	return j.svcs.UpdateDisabled(principalID, false)
+}
+
+// DisableServiceAccount will disable a service account, its API keys are rejected while disabled
+func (j *Jump) DisableServiceAccount(principalID string) (err error) {
+	return j.svcs.UpdateDisabled(principalID, true)
+}
+
+// RemoveServiceAccount will remove a service account along with its API keys, group memberships and grants
+func (j *Jump) RemoveServiceAccount(principalID string) (err error) {
+	if _, err = j.svcs.Remove(principalID); err != nil {
+		return
+	}
+
+	var as []*apikeys.APIKey
+	if as, err = j.api.GetByUser(principalID); err != nil {
+		return
+	}
+
+	for _, a := range as {
+		if _, err = j.api.Remove(a.Key); err != nil {
+			return
+		}
+	}
+
+	var groups []string
+	if groups, err = j.grps.Get(principalID); err != nil {
+		return
+	}
+
+	if len(groups) > 0 {
+		if _, err = j.grps.RemoveGroups(principalID, groups...); err != nil {
+			return
+		}
+	}
+
+	// Grants are held by the principal group, they must not carry over to a principal reusing the ID
+	if err = j.perm.RemoveGroup(principalID); err != nil {
+		return
+	}
+
+	return j.perm.RemoveResource(NewResourceKey("serviceAccount", principalID))
+}
+
+func (j *Jump) postServiceAccountCreateActions(principalID, ownerID string, groups []string) (apiKey string, err error) {
+	// Ensure first group is the principal group
+	groups = append([]string{principalID}, groups...)
+
+	// Add groups to service account
+	if _, err = j.grps.AddGroups(principalID, groups...); err != nil {
+		return
+	}
+
+	if apiKey, err = j.api.New(principalID, "primary"); err != nil {
+		return
+	}
+
+	// The owner manages the service account, the service account may only read itself
+	resourceKey := NewResourceKey("serviceAccount", principalID)
+	if err = j.SetPermission(resourceKey, ownerID, permRWD, permRWD); err != nil {
+		return
+	}
+
+	err = j.perm.SetPermissions(resourceKey, principalID, PermR)
+	return
+}
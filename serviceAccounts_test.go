@@ -0,0 +1,85 @@
+package jump
+
+import (
+	"testing"
+
+	"github.com/gdbu/jump/permissions"
+)
+
+func TestJump_RemoveServiceAccount(t *testing.T) {
+	j := newTestJump(t)
+	ownerID, _, err := j.CreateUser("owner@example.com", "password1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var principalID string
+	if principalID, _, err = j.CreateServiceAccount(ownerID, "deploys", "", "deployers"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.RemoveServiceAccount(principalID); err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []string
+	if groups, err = j.grps.Get(principalID); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) > 0 {
+		t.Fatalf("invalid groups, expected none and received %v", groups)
+	}
+
+	var userIDs []string
+	if userIDs, err = j.grps.GetByGroup("deployers"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(userIDs) > 0 {
+		t.Fatalf("invalid group members, expected none and received %v", userIDs)
+	}
+}
+
+func TestJump_RemoveServiceAccount_grants(t *testing.T) {
+	j := newTestJump(t)
+	ownerID, _, err := j.CreateUser("owner@example.com", "password1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var principalID string
+	if principalID, _, err = j.CreateServiceAccount(ownerID, "deploys", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.SetPermission(NewResourceKey("deployments", "1"), principalID, permissions.ActionRead, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.SetPermission("reports", principalID, permissions.ActionRead, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.RemoveServiceAccount(principalID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A principal reusing the ID must not inherit the grants of the removed service account
+	if _, err = j.grps.AddGroups(principalID, principalID); err != nil {
+		t.Fatal(err)
+	}
+
+	var resourceIDs []string
+	if resourceIDs, _, err = j.ListAccessibleResources(principalID, "deployments", permissions.ActionRead, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resourceIDs) > 0 {
+		t.Fatalf("invalid accessible resources, expected none and received %v", resourceIDs)
+	}
+
+	if j.perm.Can(principalID, "reports", permissions.ActionRead) {
+		t.Fatal("expected the grant on reports to be removed")
+	}
+}
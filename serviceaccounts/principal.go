@@ -0,0 +1,28 @@
+package serviceaccounts
+
+import "strings"
+
+// PrincipalPrefix is the prefix of service account principal IDs
+// Note: The prefix prevents service account IDs from colliding with user IDs
+const PrincipalPrefix = "svc::"
+
+// PrincipalID will return the principal ID for a service account ID
+func PrincipalID(id string) string {
+	return PrincipalPrefix + id
+}
+
+// ParsePrincipalID will return the service account ID of a principal ID
+func ParsePrincipalID(principalID string) (id string, ok bool) {
+	if !IsPrincipalID(principalID) {
+		return
+	}
+
+	id = strings.TrimPrefix(principalID, PrincipalPrefix)
+	ok = len(id) > 0
+	return
+}
+
+// IsPrincipalID will return whether or not a principal ID belongs to a service account
+func IsPrincipalID(principalID string) bool {
+	return strings.HasPrefix(principalID, PrincipalPrefix)
+}
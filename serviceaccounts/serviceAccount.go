@@ -0,0 +1,53 @@
+package serviceaccounts
+
+import (
+	"github.com/gdbu/errors"
+	"github.com/mojura/mojura"
+)
+
+func makeServiceAccount(ownerID, name string) (s ServiceAccount) {
+	s.OwnerID = ownerID
+	s.Name = name
+	return
+}
+
+// ServiceAccount represents a non-human principal
+// Note: Service accounts have no password and cannot log in, they authenticate using API keys
+type ServiceAccount struct {
+	mojura.Entry
+
+	// OwnerID is the ID of the user responsible for the service account
+	OwnerID string `json:"ownerID"`
+
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	Disabled bool `json:"disabled"`
+}
+
+// PrincipalID will return the principal ID of the service account
+func (s *ServiceAccount) PrincipalID() string {
+	return PrincipalID(s.ID)
+}
+
+// Validate will validate a service account
+func (s *ServiceAccount) Validate() (err error) {
+	var errs errors.ErrorList
+	if len(s.OwnerID) == 0 {
+		errs.Push(ErrInvalidOwnerID)
+	}
+
+	if len(s.Name) == 0 {
+		errs.Push(ErrInvalidName)
+	}
+
+	return errs.Err()
+}
+
+// Everything below are methods required for the mojura.Value interface
+
+// GetRelationships will get the associated relationship IDs
+func (s *ServiceAccount) GetRelationships() (r mojura.Relationships) {
+	r.Append(s.OwnerID)
+	return
+}
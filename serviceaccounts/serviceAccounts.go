@@ -0,0 +1,162 @@
+package serviceaccounts
+
+import (
+	"context"
+
+	"github.com/gdbu/errors"
+	"github.com/gdbu/jump/events"
+	"github.com/mojura/mojura"
+	"github.com/mojura/mojura/filters"
+)
+
+const (
+	// EventServiceAccountCreated is emitted with the created service account
+	EventServiceAccountCreated = "service-account-created"
+	// EventServiceAccountRemoved is emitted with the removed service account
+	EventServiceAccountRemoved = "service-account-removed"
+)
+
+const (
+	// ErrInvalidOwnerID is returned when an empty owner ID is provided
+	ErrInvalidOwnerID = errors.Error("invalid owner ID, cannot be empty")
+	// ErrInvalidName is returned when an empty name is provided
+	ErrInvalidName = errors.Error("invalid name, cannot be empty")
+	// ErrInvalidPrincipalID is returned when a principal ID does not belong to a service account
+	ErrInvalidPrincipalID = errors.Error("invalid principal ID, not a service account")
+	// ErrServiceAccountIsDisabled is returned when a service account is disabled
+	ErrServiceAccountIsDisabled = errors.Error("service account is disabled")
+)
+
+const (
+	relationshipOwners = "owners"
+)
+
+var relationships = []string{relationshipOwners}
+
+// New will return a new instance of ServiceAccounts
+func New(opts mojura.Opts, e *events.Controller) (sp *ServiceAccounts, err error) {
+	opts.Name = "serviceAccounts"
+
+	var s ServiceAccounts
+	if s.m, err = mojura.New[*ServiceAccount](opts, relationships...); err != nil {
+		return
+	}
+
+	s.events = e
+	sp = &s
+	return
+}
+
+// ServiceAccounts manages the service accounts
+type ServiceAccounts struct {
+	m      *mojura.Mojura[*ServiceAccount]
+	events *events.Controller
+}
+
+// New will create a new service account owned by the provided user
+func (s *ServiceAccounts) New(ownerID, name, description string) (created *ServiceAccount, err error) {
+	sa := makeServiceAccount(ownerID, name)
+	sa.Description = description
+	if err = sa.Validate(); err != nil {
+		return
+	}
+
+	if created, err = s.m.New(&sa); err != nil {
+		return
+	}
+
+	evt := events.MakeEvent(EventServiceAccountCreated, created)
+	s.events.New(evt)
+	return
+}
+
+// Get will get the service account which matches the principal ID
+func (s *ServiceAccounts) Get(principalID string) (sa *ServiceAccount, err error) {
+	id, ok := ParsePrincipalID(principalID)
+	if !ok {
+		err = ErrInvalidPrincipalID
+		return
+	}
+
+	return s.m.Get(id)
+}
+
+// GetByOwner will get the service accounts owned by the provided user
+func (s *ServiceAccounts) GetByOwner(ownerID string) (sas []*ServiceAccount, err error) {
+	filter := filters.Match(relationshipOwners, ownerID)
+	opts := mojura.NewFilteringOpts(filter)
+	sas, _, err = s.m.GetFiltered(opts)
+	return
+}
+
+// ForEach will iterate through all service accounts in the database
+func (s *ServiceAccounts) ForEach(fn func(*ServiceAccount) error) (err error) {
+	err = s.m.ForEach(func(_ string, sa *ServiceAccount) (err error) {
+		return fn(sa)
+	}, nil)
+
+	return
+}
+
+// UpdateOwner will transfer ownership of a service account to the provided user
+func (s *ServiceAccounts) UpdateOwner(principalID, ownerID string) (updated *ServiceAccount, err error) {
+	if len(ownerID) == 0 {
+		err = ErrInvalidOwnerID
+		return
+	}
+
+	return s.update(principalID, func(sa *ServiceAccount) (err error) {
+		sa.OwnerID = ownerID
+		return
+	})
+}
+
+// UpdateDisabled will set the disabled state of a service account
+func (s *ServiceAccounts) UpdateDisabled(principalID string, disabled bool) (err error) {
+	_, err = s.update(principalID, func(sa *ServiceAccount) (err error) {
+		sa.Disabled = disabled
+		return
+	})
+
+	return
+}
+
+// Remove will remove a service account
+func (s *ServiceAccounts) Remove(principalID string) (removed *ServiceAccount, err error) {
+	id, ok := ParsePrincipalID(principalID)
+	if !ok {
+		err = ErrInvalidPrincipalID
+		return
+	}
+
+	if err = s.m.Transaction(context.Background(), func(txn *mojura.Transaction[*ServiceAccount]) (err error) {
+		removed, err = txn.Delete(id)
+		return
+	}); err != nil {
+		return
+	}
+
+	evt := events.MakeEvent(EventServiceAccountRemoved, removed)
+	s.events.New(evt)
+	return
+}
+
+// Close will close the service accounts
+func (s *ServiceAccounts) Close() (err error) {
+	return s.m.Close()
+}
+
+func (s *ServiceAccounts) update(principalID string, fn func(*ServiceAccount) error) (updated *ServiceAccount, err error) {
+	id, ok := ParsePrincipalID(principalID)
+	if !ok {
+		err = ErrInvalidPrincipalID
+		return
+	}
+
+	err = s.m.Transaction(context.Background(), func(txn *mojura.Transaction[*ServiceAccount]) (err error) {
+		updated, err = txn.Update(id, fn)
+		return
+	})
+
+	return
+}
@@ -0,0 +1,72 @@
+package serviceaccounts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gdbu/jump/events"
+	"github.com/mojura/mojura"
+)
+
+const (
+	testOwner1 = "TEST_OWNER_1"
+	testOwner2 = "TEST_OWNER_2"
+)
+
+func TestServiceAccounts(t *testing.T) {
+	var (
+		s   *ServiceAccounts
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err = s.New("", "ci", ""); err == nil {
+		t.Fatal("expected service account without owner to fail validation")
+	}
+
+	var sa *ServiceAccount
+	if sa, err = s.New(testOwner1, "ci", "deploy pipeline"); err != nil {
+		t.Fatal(err)
+	}
+
+	principalID := sa.PrincipalID()
+	if !IsPrincipalID(principalID) {
+		t.Fatalf("expected <%s> to be a service account principal ID", principalID)
+	}
+
+	if _, err = s.Get(sa.ID); err != ErrInvalidPrincipalID {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrInvalidPrincipalID, err)
+	}
+
+	if _, err = s.UpdateOwner(principalID, testOwner2); err != nil {
+		t.Fatal(err)
+	}
+
+	var sas []*ServiceAccount
+	if sas, err = s.GetByOwner(testOwner2); err != nil {
+		t.Fatal(err)
+	} else if len(sas) != 1 || sas[0].ID != sa.ID {
+		t.Fatalf("invalid service accounts, expected <%s> and received %+v", sa.ID, sas)
+	}
+
+	if err = s.UpdateDisabled(principalID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if sa, err = s.Get(principalID); err != nil {
+		t.Fatal(err)
+	} else if !sa.Disabled {
+		t.Fatal("expected service account to be disabled")
+	}
+}
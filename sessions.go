@@ -1,23 +1,285 @@
 package jump
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/mojura/mojura"
 	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/sessions"
 )
 
-// NewSession will apply a session
+// NewSession will apply a single-factor authenticated session
+// Note: Any session presented by the request will be invalidated to prevent session fixation
 func (j *Jump) NewSession(ctx *httpserve.Context, userID string) (err error) {
+	return j.NewSessionWithLevel(ctx, userID, sessions.AssuranceSingleFactor)
+}
+
+// NewSessionWithLevel will apply a session authenticated at the provided assurance level
+// Note: Any session presented by the request will be invalidated to prevent session fixation
+func (j *Jump) NewSessionWithLevel(ctx *httpserve.Context, userID string, level sessions.AssuranceLevel) (err error) {
+	if err = j.removeRequestSession(ctx); err != nil {
+		return
+	}
+
+	var key, token string
+	if key, token, err = j.sess.NewWithLevel(userID, level); err != nil {
+		return
+	}
+
+	j.setSessionCookies(ctx, key, token)
+	ctx.Put("userID", userID)
+	return
+}
+
+// NewBearerSession will create a single-factor authenticated session and return it as an opaque bearer token
+// Note: The bearer token is accepted through the "Authorization: Bearer <token>" request header
+// Note: The session may be rotated by any later request, see BearerHeader for how to pick up the new token
+func (j *Jump) NewBearerSession(ctx *httpserve.Context, userID string) (bearer string, err error) {
 	var key, token string
 	if key, token, err = j.sess.New(userID); err != nil {
 		return
 	}
 
-	keyC := setCookie(ctx.Request().Host, CookieKey, key)
-	tokenC := setCookie(ctx.Request().Host, CookieToken, token)
+	bearer = sessions.FormatBearer(key, token)
+	ctx.Put("userID", userID)
+	return
+}
+
+// RotateSession will replace the session presented by the request with a fresh key/token pair
+// Note: The previous key/token pair is invalidated
+// Note: For bearer sessions, the new bearer token is returned within the BearerHeader response header
+func (j *Jump) RotateSession(ctx *httpserve.Context) (err error) {
+	// The session resolved by the authenticator chain is used, see getContextSession
+	var sessionID string
+	if sessionID, err = getContextSessionID(ctx); err != nil {
+		return
+	}
+
+	var key, token string
+	if key, token, err = j.sess.RotateByID(sessionID); err != nil {
+		return
+	}
+
+	j.setSessionPair(ctx, ctx.Get("authMethod"), key, token)
+	return j.setContextSession(ctx, key, token)
+}
+
+// Reauthenticate will verify the password of the current user and record a fresh
+// single-factor authentication for the session presented by the request
+// Note: The session is rotated, the previous key/token pair is invalidated
+func (j *Jump) Reauthenticate(ctx *httpserve.Context, password string) (err error) {
+	var userID string
+	if userID, err = getUserID(ctx); err != nil {
+		return
+	}
+
+	if _, err = j.usrs.Match(userID, password); err != nil {
+		return
+	}
+
+	return j.RecordAuthentication(ctx, sessions.AssuranceSingleFactor)
+}
+
+// RecordAuthentication will record a fresh authentication at the provided level for the session presented by the request
+// Note: This is intended to be called after an application has verified an additional factor (e.g. TOTP).
+// The session is rotated, the previous key/token pair is invalidated
+// Note: This should be placed after NewSetUserIDMW, the session which authenticated the request is used
+func (j *Jump) RecordAuthentication(ctx *httpserve.Context, level sessions.AssuranceLevel) (err error) {
+	// The request key/token pair is no longer valid when the session was rotated while authenticating
+	var sessionID string
+	if sessionID, err = getContextSessionID(ctx); err != nil {
+		return
+	}
+
+	var key, token string
+	if key, token, err = j.sess.ReauthenticateByID(sessionID, level); err != nil {
+		return
+	}
+
+	j.setSessionPair(ctx, ctx.Get("authMethod"), key, token)
+	return j.setContextSession(ctx, key, token)
+}
+
+// GetCSRFToken will return the CSRF token for the session presented by the request
+func (j *Jump) GetCSRFToken(ctx *httpserve.Context) (csrfToken string, err error) {
+	if sess, err := j.getContextSession(ctx); err == nil && len(sess.CSRFToken) > 0 {
+		// Session was resolved by the authenticator chain, it may have been rotated during this request
+		return sess.CSRFToken, nil
+	}
+
+	var key, token string
+	if key, token, err = j.getSessionCookies(ctx.Request()); err != nil {
+		return
+	}
+
+	return j.sess.GetCSRFToken(key, token)
+}
+
+// SetSessionLimit will set the concurrent session limit for members of a group
+// Note: When a user belongs to multiple limited groups, the most permissive limit applies
+func (j *Jump) SetSessionLimit(group string, l sessions.Limit) {
+	j.limitMux.Lock()
+	defer j.limitMux.Unlock()
+	j.sessionLimits[group] = l
+}
+
+// SetDefaultSessionLimit will set the concurrent session limit for users without a group limit
+func (j *Jump) SetDefaultSessionLimit(l sessions.Limit) {
+	j.limitMux.Lock()
+	defer j.limitMux.Unlock()
+	j.defaultSessionLimit = l
+}
+
+func (j *Jump) getSessionLimit(userID string) (l sessions.Limit) {
+	j.limitMux.RLock()
+	defer j.limitMux.RUnlock()
+	if len(j.sessionLimits) == 0 {
+		return j.defaultSessionLimit
+	}
+
+	groups, err := j.grps.Get(userID)
+	if err != nil {
+		j.out.Error(fmt.Sprintf("error getting groups for session limit of %s: %v", userID, err))
+		return j.defaultSessionLimit
+	}
+
+	var matched bool
+	for _, group := range groups {
+		gl, ok := j.sessionLimits[group]
+		switch {
+		case !ok:
+		case !matched:
+			l = gl
+			matched = true
+		case gl.IsUnlimited():
+			return gl
+		case !l.IsUnlimited() && gl.Max > l.Max:
+			l = gl
+		}
+	}
+
+	if !matched {
+		return j.defaultSessionLimit
+	}
+
+	return
+}
+
+func (j *Jump) rotateSession(ctx *httpserve.Context, method, key, token string) (err error) {
+	if key, token, err = j.sess.Rotate(key, token); err != nil {
+		return
+	}
+
+	j.setSessionPair(ctx, method, key, token)
+	return j.setContextSession(ctx, key, token)
+}
+
+// setContextSession will store the ID of the session for a key/token pair within the context
+// Note: Middleware later in the chain must use the stored session, the key/token pair presented
+// by the request is no longer valid once the session has been rotated
+func (j *Jump) setContextSession(ctx *httpserve.Context, key, token string) (err error) {
+	var sess *sessions.Session
+	if sess, err = j.sess.Get(key, token); err != nil {
+		return
+	}
+
+	ctx.Put("sessionID", sess.ID)
+	return
+}
+
+// getContextSession will return the session which authenticated the request
+func (j *Jump) getContextSession(ctx *httpserve.Context) (sess *sessions.Session, err error) {
+	var sessionID string
+	if sessionID, err = getContextSessionID(ctx); err != nil {
+		return
+	}
+
+	return j.sess.GetByID(sessionID)
+}
+
+// getContextSessionID will return the ID of the session which authenticated the request
+func getContextSessionID(ctx *httpserve.Context) (sessionID string, err error) {
+	if sessionID = ctx.Get("sessionID"); len(sessionID) == 0 {
+		err = sessions.ErrSessionDoesNotExist
+		return
+	}
+
+	return
+}
+
+// removeRequestSession will remove the session which authenticated the request, falling back to
+// the session associated with the request cookies (if any)
+func (j *Jump) removeRequestSession(ctx *httpserve.Context) (err error) {
+	if sessionID, err := getContextSessionID(ctx); err == nil {
+		// The request cookies are no longer valid when the session was rotated while authenticating
+		ctx.Put("sessionID", "")
+		return ignoreNotFound(j.sess.RemoveByID(sessionID))
+	}
+
+	var key, token string
+	if key, token, err = j.getSessionCookies(ctx.Request()); err != nil {
+		// No session cookies are present, nothing to remove
+		return nil
+	}
+
+	return ignoreNotFound(j.sess.Remove(key, token))
+}
+
+// ignoreNotFound will return nil for entries which have already been removed
+func ignoreNotFound(err error) error {
+	switch err {
+	case nil, mojura.ErrEntryNotFound:
+		return nil
+	default:
+		return err
+	}
+}
+
+// setSessionPair will deliver a key/token pair to the client using the provided auth method
+func (j *Jump) setSessionPair(ctx *httpserve.Context, method, key, token string) {
+	if method == AuthMethodBearer {
+		h := ctx.Writer().Header()
+		h.Set(BearerHeader, sessions.FormatBearer(key, token))
+		// Cross-origin clients can only read response headers which are explicitly exposed
+		exposeHeader(h, BearerHeader)
+		return
+	}
+
+	j.setSessionCookies(ctx, key, token)
+}
+
+// exposeHeader will add a header to Access-Control-Expose-Headers, unless it is already exposed
+func exposeHeader(h http.Header, name string) {
+	for _, value := range h.Values("Access-Control-Expose-Headers") {
+		for _, exposed := range strings.Split(value, ",") {
+			if exposed = strings.TrimSpace(exposed); exposed == "*" || strings.EqualFold(exposed, name) {
+				return
+			}
+		}
+	}
+
+	h.Add("Access-Control-Expose-Headers", name)
+}
+
+func (j *Jump) getSessionCookies(req *http.Request) (key, token string, err error) {
+	if key, err = getCookieValue(req, j.cookies.keyName()); err != nil {
+		return
+	}
+
+	if token, err = getCookieValue(req, j.cookies.tokenName()); err != nil {
+		return
+	}
+
+	return
+}
+
+func (j *Jump) setSessionCookies(ctx *httpserve.Context, key, token string) {
+	keyC := j.cookies.setCookie(j.cookies.keyName(), key)
+	tokenC := j.cookies.setCookie(j.cookies.tokenName(), token)
 
 	http.SetCookie(ctx.Writer(), &keyC)
 	http.SetCookie(ctx.Writer(), &tokenC)
-	ctx.Put("userID", userID)
-	return
 }
@@ -0,0 +1,25 @@
+package sessions
+
+// AssuranceLevel represents the strength of the authentication which established a session
+type AssuranceLevel uint8
+
+const (
+	// AssuranceNone represents a session without a recorded authentication
+	AssuranceNone AssuranceLevel = iota
+	// AssuranceSingleFactor represents a session authenticated by a single factor (e.g. password or SSO)
+	AssuranceSingleFactor
+	// AssuranceMultiFactor represents a session authenticated by multiple factors
+	AssuranceMultiFactor
+)
+
+// String will return the string representation of an assurance level
+func (a AssuranceLevel) String() string {
+	switch a {
+	case AssuranceSingleFactor:
+		return "single-factor"
+	case AssuranceMultiFactor:
+		return "multi-factor"
+	default:
+		return "none"
+	}
+}
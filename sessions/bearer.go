@@ -0,0 +1,37 @@
+package sessions
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/gdbu/errors"
+)
+
+const (
+	// ErrInvalidBearerToken is returned when a bearer token cannot be parsed
+	ErrInvalidBearerToken = errors.Error("invalid bearer token")
+)
+
+const bearerSeparator = ":"
+
+// FormatBearer will return an opaque bearer token representing a key/token pair
+func FormatBearer(key, token string) (bearer string) {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + bearerSeparator + token))
+}
+
+// ParseBearer will return the key/token pair represented by an opaque bearer token
+func ParseBearer(bearer string) (key, token string, err error) {
+	var bs []byte
+	if bs, err = base64.RawURLEncoding.DecodeString(bearer); err != nil {
+		err = ErrInvalidBearerToken
+		return
+	}
+
+	var ok bool
+	if key, token, ok = strings.Cut(string(bs), bearerSeparator); !ok || len(key) == 0 || len(token) == 0 {
+		err = ErrInvalidBearerToken
+		return
+	}
+
+	return
+}
@@ -0,0 +1,27 @@
+package sessions
+
+// LimitPolicy represents the action taken when a user has reached their concurrent session limit
+type LimitPolicy uint8
+
+const (
+	// LimitPolicyEvict will remove the least recently used session(s) to make room for the new session
+	LimitPolicyEvict LimitPolicy = iota
+	// LimitPolicyReject will reject the new session with ErrSessionLimitReached
+	LimitPolicyReject
+)
+
+// Limit represents a concurrent session limit
+type Limit struct {
+	// Max is the maximum number of concurrent sessions, zero represents no limit
+	Max int `json:"max" toml:"max"`
+	// Policy is the action taken when the limit has been reached
+	Policy LimitPolicy `json:"policy" toml:"policy"`
+}
+
+// IsUnlimited will return whether or not the limit is unset
+func (l Limit) IsUnlimited() bool {
+	return l.Max <= 0
+}
+
+// LimitFunc will return the concurrent session limit for a given user ID
+type LimitFunc func(userID string) Limit
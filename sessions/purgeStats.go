@@ -0,0 +1,17 @@
+package sessions
+
+import "time"
+
+// PurgeStats represents the statistics of the session purge worker
+type PurgeStats struct {
+	// Runs is the number of purges which have occurred
+	Runs int64 `json:"runs"`
+	// LastRunAt is the unix timestamp of the most recent purge
+	LastRunAt int64 `json:"lastRunAt"`
+	// LastDuration is the duration of the most recent purge
+	LastDuration time.Duration `json:"lastDuration"`
+	// LastPurged is the number of sessions removed by the most recent purge
+	LastPurged int64 `json:"lastPurged"`
+	// TotalPurged is the number of sessions removed by all purges
+	TotalPurged int64 `json:"totalPurged"`
+}
@@ -1,15 +1,18 @@
 package sessions
 
 import (
+	"crypto/subtle"
 	"time"
 
 	"github.com/mojura/mojura"
 )
 
-func makeSession(key, userID string) (s Session) {
+func makeSession(key, userID string, level AssuranceLevel) (s Session) {
 	s.Key = key
 	s.UserID = userID
+	s.CSRFToken = newCSRFToken()
 	s.setAction()
+	s.setAuthenticated(level)
 	return
 }
 
@@ -23,12 +26,58 @@ type Session struct {
 	UserID string `json:"userID"`
 
 	LastUsedAt int64 `json:"lastUsedAt"`
+
+	// CSRFToken is the synchronizer token used to validate state-changing requests
+	CSRFToken string `json:"csrfToken"`
+
+	// AuthenticatedAt is the unix timestamp of the most recent authentication for this session
+	AuthenticatedAt int64 `json:"authenticatedAt"`
+	// AssuranceLevel is the strength of the most recent authentication for this session
+	AssuranceLevel AssuranceLevel `json:"assuranceLevel"`
+
+	// RotationRequired is set when the session ID must be re-issued on next use (e.g. privilege change)
+	RotationRequired bool `json:"rotationRequired,omitempty"`
+}
+
+// IsCSRFMatch will return whether or not the provided token matches the session's CSRF token
+func (s *Session) IsCSRFMatch(token string) (match bool) {
+	if len(s.CSRFToken) == 0 || len(token) == 0 {
+		return
+	}
+
+	return subtle.ConstantTimeCompare([]byte(s.CSRFToken), []byte(token)) == 1
+}
+
+// IsFresh will return whether or not the session was authenticated within the provided max age at the provided level (or higher)
+func (s *Session) IsFresh(maxAge time.Duration, level AssuranceLevel) (fresh bool) {
+	if s.AssuranceLevel < level {
+		return
+	}
+
+	authenticatedAt := time.Unix(s.AuthenticatedAt, 0)
+	return time.Since(authenticatedAt) <= maxAge
+}
+
+// IsExpired will return whether or not the session has not been used within the SessionTimeout
+func (s *Session) IsExpired(now time.Time) (expired bool) {
+	return now.Unix() > s.LastUsedAt+SessionTimeout
+}
+
+// sanitize will remove the credentials of the session, used before a session is published to event subscribers
+func (s *Session) sanitize() {
+	s.Key = ""
+	s.CSRFToken = ""
 }
 
 func (s *Session) setAction() {
 	s.LastUsedAt = time.Now().Unix()
 }
 
+func (s *Session) setAuthenticated(level AssuranceLevel) {
+	s.AuthenticatedAt = time.Now().Unix()
+	s.AssuranceLevel = level
+}
+
 // mojura.Value interface methods below
 
 // GetID will get the message ID
@@ -44,6 +93,7 @@ func (s *Session) GetUpdatedAt() (updatedAt int64) { return s.UpdatedAt }
 func (s *Session) GetRelationships() (r mojura.Relationships) {
 	r.Append(s.Key)
 	r.Append(s.UserID)
+	r.Append(formatHourBucket(s.LastUsedAt))
 	return
 }
 
@@ -0,0 +1,15 @@
+package sessions
+
+func makeSessionRotation(previous, current *Session) (r SessionRotation) {
+	r.UserID = current.UserID
+	r.PreviousSessionID = previous.ID
+	r.SessionID = current.ID
+	return
+}
+
+// SessionRotation represents a session which was re-issued with a fresh key/token pair
+type SessionRotation struct {
+	UserID            string `json:"userID"`
+	PreviousSessionID string `json:"previousSessionID"`
+	SessionID         string `json:"sessionID"`
+}
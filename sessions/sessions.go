@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/gdbu/errors"
+	"github.com/gdbu/jump/events"
 	"github.com/gdbu/uuid"
 	"github.com/mojura/mojura"
 	"github.com/mojura/mojura/filters"
@@ -15,35 +17,68 @@ import (
 const (
 	// ErrSessionDoesNotExist is returned when an invalid token/key pair is presented
 	ErrSessionDoesNotExist = errors.Error("session with that token/key pair does not exist")
+	// ErrSessionLimitReached is returned when a user has reached their concurrent session limit
+	ErrSessionLimitReached = errors.Error("concurrent session limit reached")
+	// ErrInvalidAssuranceLevel is returned when an authentication is recorded without an assurance level
+	ErrInvalidAssuranceLevel = errors.Error("invalid assurance level, cannot be none")
+	// ErrSessionExpired is returned when a session has not been used within the SessionTimeout
+	ErrSessionExpired = errors.Error("session has expired")
+)
+
+const (
+	// EventSessionCreated is emitted with the sanitized *Session when a session is created (e.g. a new login)
+	EventSessionCreated = "session-created"
+	// EventSessionRemoved is emitted with the sanitized *Session when a session is removed (e.g. a logout)
+	EventSessionRemoved = "session-removed"
+	// EventSessionRotated is emitted with SessionRotation when a session is re-issued with a fresh key/token pair
+	// Note: Rotation continues an existing login (e.g. privilege change or reauthentication), it is not a new login
+	EventSessionRotated = "session-rotated"
+	// EventSessionExpired is emitted with the sanitized *Session when a session is purged
+	EventSessionExpired = "session-expired"
+	// EventSessionEvicted is emitted with the sanitized *Session when a session is evicted by a session limit
+	EventSessionEvicted = "session-evicted"
+	// EventUserSessionsInvalidated is emitted with UserSessions (sanitized) when all sessions of a user are invalidated
+	EventUserSessionsInvalidated = "user-sessions-invalidated"
 )
 
 const (
 	// SessionTimeout (in seconds) is the ttl for sessions, an action will refresh the duration
 	SessionTimeout = 60 * 60 * 24 * 7 // 7 days
+	// PurgeBatchSize is the maximum number of sessions deleted within a single purge transaction
+	PurgeBatchSize = 1000
 )
 
 const (
-	relationshipKeys  = "keys"
-	relationshipUsers = "users"
+	relationshipKeys          = "keys"
+	relationshipUsers         = "users"
+	relationshipLastUsedHours = "lastUsedHours"
 )
 
 var (
-	relationships = []string{relationshipKeys, relationshipUsers}
+	relationships = []string{relationshipKeys, relationshipUsers, relationshipLastUsedHours}
 )
 
 // New will return a new instance of sessions
-func New(opts mojura.Opts) (sp *Sessions, err error) {
+func New(opts mojura.Opts, e *events.Controller) (sp *Sessions, err error) {
 	var s Sessions
 	opts.Name = "sessions"
 	s.out = mojura.NewLogger()
+	s.events = e
 	if s.c, err = mojura.New[*Session](opts, relationships...); err != nil {
 		return
 	}
 
 	s.g = uuid.NewGenerator()
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	if !opts.IsMirror {
+		if err = s.migrate(); err != nil {
+			err = fmt.Errorf("error migrating sessions: %v", err)
+			return
+		}
+
 		// Start purge loop
+		s.wg.Add(1)
 		go s.loop()
 	}
 
@@ -56,6 +91,18 @@ type Sessions struct {
 	out mojura.Logger
 	c   *mojura.Mojura[*Session]
 	g   *uuid.Generator
+
+	events *events.Controller
+
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	statsMux sync.Mutex
+	stats    PurgeStats
+
+	limitMux sync.RWMutex
+	limitFn  LimitFunc
 }
 
 func (s *Sessions) newKeyToken() (key, token string) {
@@ -69,11 +116,54 @@ func (s *Sessions) newKeyToken() (key, token string) {
 	return
 }
 
-func (s *Sessions) makeSession(key, token, userID string) Session {
+func (s *Sessions) makeSession(key, token, userID string, level AssuranceLevel) Session {
 	// Set session key
 	sessionKey := makeSessionKey(key, token)
 	// Create new session
-	return makeSession(sessionKey, userID)
+	return makeSession(sessionKey, userID, level)
+}
+
+func (s *Sessions) getLimit(userID string) (l Limit) {
+	s.limitMux.RLock()
+	defer s.limitMux.RUnlock()
+	if s.limitFn == nil {
+		return
+	}
+
+	return s.limitFn(userID)
+}
+
+// enforceLimit will ensure there is room for a new session for the provided user
+func (s *Sessions) enforceLimit(txn *mojura.Transaction[*Session], userID string) (evicted []*Session, err error) {
+	l := s.getLimit(userID)
+	if l.IsUnlimited() {
+		return
+	}
+
+	var ss []*Session
+	if ss, err = s.getActiveByUserID(txn, userID); err != nil {
+		return
+	}
+
+	if len(ss) < l.Max {
+		return
+	}
+
+	if l.Policy == LimitPolicyReject {
+		err = ErrSessionLimitReached
+		return
+	}
+
+	// Sessions are sorted by most recently used, evict from the tail to make room for one more
+	for _, sess := range ss[l.Max-1:] {
+		if _, err = txn.Delete(sess.ID); err != nil {
+			return
+		}
+
+		evicted = append(evicted, sess)
+	}
+
+	return
 }
 
 func (s *Sessions) getByKey(txn *mojura.Transaction[*Session], key string) (sp *Session, err error) {
@@ -97,40 +187,195 @@ func (s *Sessions) getByUserID(txn *mojura.Transaction[*Session], userID string)
 	return
 }
 
+// getActiveByUserID will return the sessions of a user which have not expired
+// Note: Expired sessions are left for the purge, they do not count toward (or get evicted by) the session limit
+func (s *Sessions) getActiveByUserID(txn *mojura.Transaction[*Session], userID string) (active []*Session, err error) {
+	var ss []*Session
+	if ss, err = s.getByUserID(txn, userID); err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, sess := range ss {
+		if sess.IsExpired(now) {
+			continue
+		}
+
+		active = append(active, sess)
+	}
+
+	return
+}
+
+func (s *Sessions) updateStats(start time.Time, purged int64) {
+	s.statsMux.Lock()
+	defer s.statsMux.Unlock()
+	s.stats.Runs++
+	s.stats.LastRunAt = start.Unix()
+	s.stats.LastDuration = time.Since(start)
+	s.stats.LastPurged = purged
+	s.stats.TotalPurged += purged
+}
+
+// migrate will reindex the sessions relationships when the expiry index is missing
+// Note: Sessions persisted before the expiry index existed would otherwise never be purged
+func (s *Sessions) migrate() (err error) {
+	var missing bool
+	if err = s.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+		missing, err = s.isExpiryIndexMissing(txn)
+		return
+	}); err != nil || !missing {
+		return
+	}
+
+	s.out.Info("expiry index is missing, reindexing sessions")
+	return s.Reindex()
+}
+
+// isExpiryIndexMissing will return whether or not the first session is absent from the expiry index
+func (s *Sessions) isExpiryIndexMissing(txn *mojura.Transaction[*Session]) (missing bool, err error) {
+	var first *Session
+	if err = txn.ForEach(func(_ string, sess *Session) (err error) {
+		first = sess
+		return mojura.Break
+	}, nil); err != nil || first == nil {
+		return
+	}
+
+	filter := filters.Match(relationshipLastUsedHours, formatHourBucket(first.LastUsedAt))
+	switch _, err = txn.GetFirst(mojura.NewFilteringOpts(filter)); err {
+	case mojura.ErrEntryNotFound:
+		return true, nil
+	default:
+		return
+	}
+}
+
+// getActive will return the session for a session key, ensuring it has not expired
+func (s *Sessions) getActive(txn *mojura.Transaction[*Session], sessionKey string) (sp *Session, err error) {
+	if sp, err = s.getByKey(txn, sessionKey); err != nil {
+		return
+	}
+
+	if sp.IsExpired(time.Now()) {
+		// Expired sessions are rejected even when the purge loop has yet to remove them
+		sp = nil
+		err = ErrSessionExpired
+	}
+
+	return
+}
+
+func (s *Sessions) notify(key string, value interface{}) {
+	evt := events.MakeEvent(key, value)
+	s.events.New(evt)
+}
+
 func (s *Sessions) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
 	for {
 		oldest := time.Now().Add(time.Second * -SessionTimeout).Unix()
 		if err := s.Purge(oldest); err != nil {
 			s.out.Error(fmt.Sprintf("error purging: %v", err))
 		}
 
-		time.Sleep(time.Minute)
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
-// purge will purge all entries older than the oldest value
-func (s *Sessions) purge(txn *mojura.Transaction[*Session], oldest int64) (err error) {
-	err = txn.ForEach(func(sessionID string, session *Session) (err error) {
-		if session.LastUsedAt >= oldest {
+// purge will purge up to PurgeBatchSize entries which were last used in an hour bucket prior to the oldest value
+func (s *Sessions) purge(txn *mojura.Transaction[*Session], oldest int64) (purged []*Session, err error) {
+	filter := filters.LessThan(relationshipLastUsedHours, formatHourBucket(oldest))
+	opts := mojura.NewFilteringOpts(filter)
+	opts.Limit = PurgeBatchSize
+
+	var ids []string
+	if ids, _, err = txn.GetFilteredIDs(opts); err != nil {
+		return
+	}
+
+	for _, sessionID := range ids {
+		var removed *Session
+		if removed, err = txn.Delete(sessionID); err != nil {
 			return
 		}
 
-		_, err = txn.Delete(sessionID)
-		return
-	}, nil)
+		purged = append(purged, removed)
+	}
 
 	return
 }
 
 // Remove will invalidate a provided key/token pair session
-func (s *Sessions) invalidateUser(txn *mojura.Transaction[*Session], userID string) (err error) {
+func (s *Sessions) invalidateUser(txn *mojura.Transaction[*Session], userID string) (removed []*Session, err error) {
+	if removed, err = s.getByUserID(txn, userID); err != nil {
+		return
+	}
+
+	for _, sess := range removed {
+		if _, err = txn.Delete(sess.ID); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// rotate will replace the session matching the provided session key with a fresh key/token pair
+// Note: When the provided level is AssuranceNone, the authentication state of the previous session is retained.
+// Otherwise, the higher of the provided level and the level of the previous session is kept.
+func (s *Sessions) rotate(txn *mojura.Transaction[*Session], sessionKey string, level AssuranceLevel) (removed, created *Session, key, token string, err error) {
+	if removed, err = s.getActive(txn, sessionKey); err != nil {
+		return
+	}
+
+	if _, err = txn.Delete(removed.ID); err != nil {
+		return
+	}
+
+	// Set key/token
+	key, token = s.newKeyToken()
+	// Create replacement session for the same user
+	session := s.makeSession(key, token, removed.UserID, level)
+	if len(removed.CSRFToken) > 0 {
+		// Retain the CSRF token, clients hold it independently of the key/token pair
+		session.CSRFToken = removed.CSRFToken
+	}
+
+	switch {
+	case level == AssuranceNone:
+		session.AuthenticatedAt = removed.AuthenticatedAt
+		session.AssuranceLevel = removed.AssuranceLevel
+	case removed.AssuranceLevel > level:
+		// Reauthenticating at a lower level does not downgrade the session
+		session.AssuranceLevel = removed.AssuranceLevel
+	}
+
+	created, err = txn.New(&session)
+	return
+}
+
+// markForRotation will flag all sessions associated with a user to be rotated on next use
+func (s *Sessions) markForRotation(txn *mojura.Transaction[*Session], userID string) (err error) {
 	var ss []*Session
 	if ss, err = s.getByUserID(txn, userID); err != nil {
 		return
 	}
 
 	for _, sess := range ss {
-		if _, err = txn.Delete(sess.ID); err != nil {
+		if sess.RotationRequired {
+			continue
+		}
+
+		sess.RotationRequired = true
+		if _, err = txn.Put(sess.ID, sess); err != nil {
 			return
 		}
 	}
@@ -139,23 +384,71 @@ func (s *Sessions) invalidateUser(txn *mojura.Transaction[*Session], userID stri
 }
 
 // Purge will purge all entries oldest than the oldest value
+// Note: Expiry is indexed by the hour, entries last used within the same hour as the oldest
+// value will be purged on a subsequent run. Deletion occurs in batches of PurgeBatchSize to
+// avoid blocking writers. Sessions persisted before the expiry index existed are reindexed by New.
 func (s *Sessions) Purge(oldest int64) (err error) {
-	err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
-		return s.purge(txn, oldest)
-	})
+	start := time.Now()
+
+	var purged int64
+	for {
+		var removed []*Session
+		if err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+			removed, err = s.purge(txn, oldest)
+			return
+		}); err != nil {
+			break
+		}
+
+		for _, sess := range removed {
+			sess.sanitize()
+			s.notify(EventSessionExpired, sess)
+		}
 
+		if purged += int64(len(removed)); len(removed) < PurgeBatchSize {
+			break
+		}
+	}
+
+	s.updateStats(start, purged)
 	return
 }
 
-// New will create a new token/key pair
+// Stats will return the purge statistics
+func (s *Sessions) Stats() (stats PurgeStats) {
+	s.statsMux.Lock()
+	defer s.statsMux.Unlock()
+	return s.stats
+}
+
+// Reindex will rebuild the sessions relationships
+func (s *Sessions) Reindex() (err error) {
+	return s.c.Reindex(context.Background())
+}
+
+// New will create a new token/key pair for a single-factor authenticated user
 func (s *Sessions) New(userID string) (key, token string, err error) {
+	return s.NewWithLevel(userID, AssuranceSingleFactor)
+}
+
+// NewWithLevel will create a new token/key pair for a user authenticated at the provided assurance level
+func (s *Sessions) NewWithLevel(userID string, level AssuranceLevel) (key, token string, err error) {
 	// Set key/token
 	key, token = s.newKeyToken()
 	// Create new session
-	session := s.makeSession(key, token, userID)
+	session := s.makeSession(key, token, userID, level)
+
+	var (
+		created *Session
+		evicted []*Session
+	)
 
 	if err = s.c.Batch(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
-		_, err = txn.New(&session)
+		if evicted, err = s.enforceLimit(txn, userID); err != nil {
+			return
+		}
+
+		created, err = txn.New(&session)
 		return
 	}); err != nil {
 		key = ""
@@ -163,18 +456,64 @@ func (s *Sessions) New(userID string) (key, token string, err error) {
 		return
 	}
 
+	for _, sess := range evicted {
+		sess.sanitize()
+		s.notify(EventSessionEvicted, sess)
+	}
+
+	created.sanitize()
+	s.notify(EventSessionCreated, created)
 	return
 }
 
 // Get will retrieve the user id associated with a provided key/token pair
+// Note: ErrSessionExpired is returned for sessions which have not been used within the SessionTimeout
 func (s *Sessions) Get(key, token string) (sp *Session, err error) {
 	// Create session key from the key/token pair
 	sessionKey := makeSessionKey(key, token)
 	err = s.c.ReadTransaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
-		if sp, err = s.getByKey(txn, sessionKey); err != nil {
+		sp, err = s.getActive(txn, sessionKey)
+		return
+	})
+
+	return
+}
+
+// GetByID will retrieve a session by session ID
+func (s *Sessions) GetByID(sessionID string) (sp *Session, err error) {
+	if sp, err = s.c.Get(sessionID); err != nil {
+		return
+	}
+
+	if sp.IsExpired(time.Now()) {
+		sp = nil
+		err = ErrSessionExpired
+	}
+
+	return
+}
+
+// GetCSRFToken will return the CSRF token associated with a provided key/token pair
+// Note: Sessions created before CSRF tokens were introduced will have one generated
+func (s *Sessions) GetCSRFToken(key, token string) (csrfToken string, err error) {
+	// Create session key from the key/token pair
+	sessionKey := makeSessionKey(key, token)
+	err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+		var sp *Session
+		if sp, err = s.getActive(txn, sessionKey); err != nil {
+			return
+		}
+
+		if csrfToken = sp.CSRFToken; len(csrfToken) > 0 {
+			return
+		}
+
+		sp.CSRFToken = newCSRFToken()
+		if _, err = txn.Put(sp.ID, sp); err != nil {
 			return
 		}
 
+		csrfToken = sp.CSRFToken
 		return
 	})
 
@@ -187,7 +526,7 @@ func (s *Sessions) Refesh(key, token string) (err error) {
 	sessionKey := makeSessionKey(key, token)
 	err = s.c.Batch(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
 		var sp *Session
-		if sp, err = s.getByKey(txn, sessionKey); err != nil {
+		if sp, err = s.getActive(txn, sessionKey); err != nil {
 			return
 		}
 
@@ -214,14 +553,119 @@ func (s *Sessions) GetByUserID(userID string) (ss []*Session, err error) {
 func (s *Sessions) Remove(key, token string) (err error) {
 	// Create session key from the key/token pair
 	sessionKey := makeSessionKey(key, token)
-	err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+
+	var removed *Session
+	if err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
 		var sp *Session
 		if sp, err = s.getByKey(txn, sessionKey); err != nil {
 			return
 		}
 
-		_, err = txn.Delete(sp.ID)
+		removed, err = txn.Delete(sp.ID)
+		return
+	}); err != nil {
+		return
+	}
+
+	removed.sanitize()
+	s.notify(EventSessionRemoved, removed)
+	return
+}
+
+// RemoveByID will invalidate a session by session ID
+// Note: This is intended for sessions resolved earlier within a request, whose key/token pair may have since been rotated
+func (s *Sessions) RemoveByID(sessionID string) (err error) {
+	var removed *Session
+	if err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+		removed, err = txn.Delete(sessionID)
+		return
+	}); err != nil {
+		return
+	}
+
+	removed.sanitize()
+	s.notify(EventSessionRemoved, removed)
+	return
+}
+
+// Rotate will invalidate a provided key/token pair session and return a new key/token pair for the same user
+func (s *Sessions) Rotate(key, token string) (newKey, newToken string, err error) {
+	return s.rotateWithLevel(key, token, AssuranceNone)
+}
+
+// RotateByID will invalidate a session by session ID and return a new key/token pair for the same user
+// Note: See RemoveByID for context on session ID usage
+func (s *Sessions) RotateByID(sessionID string) (newKey, newToken string, err error) {
+	return s.rotateByIDWithLevel(sessionID, AssuranceNone)
+}
+
+// Reauthenticate will record a fresh authentication at the provided level for a key/token pair session
+// Note: The session is rotated, the previous key/token pair is invalidated. A session previously
+// authenticated at a higher level retains that level.
+func (s *Sessions) Reauthenticate(key, token string, level AssuranceLevel) (newKey, newToken string, err error) {
+	if level == AssuranceNone {
+		err = ErrInvalidAssuranceLevel
+		return
+	}
+
+	return s.rotateWithLevel(key, token, level)
+}
+
+// ReauthenticateByID will record a fresh authentication at the provided level for a session by session ID
+// Note: See RemoveByID for context on session ID usage
+func (s *Sessions) ReauthenticateByID(sessionID string, level AssuranceLevel) (newKey, newToken string, err error) {
+	if level == AssuranceNone {
+		err = ErrInvalidAssuranceLevel
+		return
+	}
+
+	return s.rotateByIDWithLevel(sessionID, level)
+}
+
+func (s *Sessions) rotateWithLevel(key, token string, level AssuranceLevel) (newKey, newToken string, err error) {
+	// Create session key from the key/token pair
+	sessionKey := makeSessionKey(key, token)
+	return s.rotateSessionKey(func(_ *mojura.Transaction[*Session]) (string, error) {
+		return sessionKey, nil
+	}, level)
+}
+
+func (s *Sessions) rotateByIDWithLevel(sessionID string, level AssuranceLevel) (newKey, newToken string, err error) {
+	return s.rotateSessionKey(func(txn *mojura.Transaction[*Session]) (sessionKey string, err error) {
+		var sp *Session
+		if sp, err = txn.Get(sessionID); err != nil {
+			return
+		}
+
+		return sp.Key, nil
+	}, level)
+}
+
+// rotateSessionKey will rotate the session matching the session key returned by the provided func
+func (s *Sessions) rotateSessionKey(getSessionKey func(*mojura.Transaction[*Session]) (string, error), level AssuranceLevel) (newKey, newToken string, err error) {
+	var removed, created *Session
+	if err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+		var sessionKey string
+		if sessionKey, err = getSessionKey(txn); err != nil {
+			return
+		}
+
+		removed, created, newKey, newToken, err = s.rotate(txn, sessionKey, level)
 		return
+	}); err != nil {
+		newKey = ""
+		newToken = ""
+		return
+	}
+
+	s.notify(EventSessionRotated, makeSessionRotation(removed, created))
+	return
+}
+
+// MarkForRotation will flag all sessions associated with a user to be rotated on their next use
+func (s *Sessions) MarkForRotation(userID string) (err error) {
+	err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+		return s.markForRotation(txn, userID)
 	})
 
 	return
@@ -229,14 +673,39 @@ func (s *Sessions) Remove(key, token string) (err error) {
 
 // InvalidateUser will invalidate all sessions associated with a user
 func (s *Sessions) InvalidateUser(userID string) (err error) {
-	err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
-		return s.invalidateUser(txn, userID)
-	})
+	var removed []*Session
+	if err = s.c.Transaction(context.Background(), func(txn *mojura.Transaction[*Session]) (err error) {
+		removed, err = s.invalidateUser(txn, userID)
+		return
+	}); err != nil {
+		return
+	}
 
+	for _, sess := range removed {
+		sess.sanitize()
+	}
+
+	s.notify(EventUserSessionsInvalidated, makeUserSessions(userID, removed))
 	return
 }
 
-// Close will close an instance of Sessions
+// SetLimit will set a concurrent session limit which applies to all users
+func (s *Sessions) SetLimit(l Limit) {
+	s.SetLimitFunc(func(_ string) Limit {
+		return l
+	})
+}
+
+// SetLimitFunc will set the func used to determine the concurrent session limit for a user
+func (s *Sessions) SetLimitFunc(fn LimitFunc) {
+	s.limitMux.Lock()
+	defer s.limitMux.Unlock()
+	s.limitFn = fn
+}
+
+// Close will stop the purge loop and close an instance of Sessions
 func (s *Sessions) Close() (err error) {
+	s.cancel()
+	s.wg.Wait()
 	return s.c.Close()
 }
@@ -3,7 +3,9 @@ package sessions
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/gdbu/jump/events"
 	"github.com/mojura/mojura"
 )
 
@@ -26,7 +28,7 @@ func TestSessions(t *testing.T) {
 
 	var opts mojura.Opts
 	opts.Dir = "./test_data"
-	if s, err = New(opts); err != nil {
+	if s, err = New(opts, events.New()); err != nil {
 		t.Fatal(err)
 	}
 
@@ -69,7 +71,7 @@ func TestSessions(t *testing.T) {
 	}
 
 	// Re-open sessions from snapshot
-	if s, err = New(opts); err != nil {
+	if s, err = New(opts, events.New()); err != nil {
 		t.Fatal(err)
 	}
 
@@ -93,3 +95,494 @@ func TestSessions(t *testing.T) {
 		t.Fatalf("invalid user match, expected %s and received %s", testUser3, mu.UserID)
 	}
 }
+
+func TestSessions_Rotate(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var key, token string
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.MarkForRotation(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var sess *Session
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	} else if !sess.RotationRequired {
+		t.Fatal("expected session to be marked for rotation")
+	}
+
+	var newKey, newToken string
+	if newKey, newToken, err = s.Rotate(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Get(key, token); err != mojura.ErrEntryNotFound {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", mojura.ErrEntryNotFound, err)
+	}
+
+	if sess, err = s.Get(newKey, newToken); err != nil {
+		t.Fatal(err)
+	} else if sess.UserID != testUser1 {
+		t.Fatalf("invalid user match, expected %s and received %s", testUser1, sess.UserID)
+	} else if sess.RotationRequired {
+		t.Fatal("expected rotated session to not require rotation")
+	}
+}
+
+func TestSession_IsCSRFMatch(t *testing.T) {
+	s := makeSession("key", testUser1, AssuranceSingleFactor)
+	if !s.IsCSRFMatch(s.CSRFToken) {
+		t.Fatal("expected CSRF token to match")
+	}
+
+	if s.IsCSRFMatch("") {
+		t.Fatal("expected empty CSRF token to not match")
+	}
+
+	if s.IsCSRFMatch(newCSRFToken()) {
+		t.Fatal("expected different CSRF token to not match")
+	}
+}
+
+func TestSessions_Purge(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var staleKey, staleToken string
+	if staleKey, staleToken, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var freshKey, freshToken string
+	if freshKey, freshToken, err = s.New(testUser2); err != nil {
+		t.Fatal(err)
+	}
+
+	var stale *Session
+	if stale, err = s.Get(staleKey, staleToken); err != nil {
+		t.Fatal(err)
+	}
+
+	stale.LastUsedAt = time.Now().Add(time.Hour * -48).Unix()
+	if _, err = s.c.Put(stale.ID, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Purge(time.Now().Add(time.Hour * -24).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Get(staleKey, staleToken); err != mojura.ErrEntryNotFound {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", mojura.ErrEntryNotFound, err)
+	}
+
+	if _, err = s.Get(freshKey, freshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := s.Stats(); stats.TotalPurged != 1 {
+		t.Fatalf("invalid stats, expected 1 purged session and received %+v", stats)
+	}
+}
+
+func TestSessions_Get_expired(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var key, token string
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var sess *Session
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	sess.LastUsedAt = time.Now().Add(-time.Second * (SessionTimeout + 60)).Unix()
+	if _, err = s.c.Put(sess.ID, sess); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Get(key, token); err != ErrSessionExpired {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrSessionExpired, err)
+	}
+
+	if _, err = s.GetByID(sess.ID); err != ErrSessionExpired {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrSessionExpired, err)
+	}
+
+	if err = s.Refesh(key, token); err != ErrSessionExpired {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrSessionExpired, err)
+	}
+}
+
+func TestSessions_migrate(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	opts.Name = "sessions"
+
+	// Persist a session without the expiry index, as stored prior to its introduction
+	var legacy *mojura.Mojura[*legacySession]
+	if legacy, err = mojura.New[*legacySession](opts, relationshipKeys, relationshipUsers); err != nil {
+		t.Fatal(err)
+	}
+
+	var stale legacySession
+	stale.Session = makeSession(makeSessionKey("key", "token"), testUser1, AssuranceSingleFactor)
+	stale.LastUsedAt = time.Now().Add(time.Hour * -48).Unix()
+	if _, err = legacy.New(&stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = legacy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err = s.Purge(time.Now().Add(time.Hour * -24).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := s.Stats(); stats.TotalPurged != 1 {
+		t.Fatalf("invalid stats, expected the legacy session to be purged and received %+v", stats)
+	}
+}
+
+func TestSessions_events(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	received := make(chan events.Event, 8)
+	e := events.New()
+	e.Subscribe(func(evt events.Event) {
+		received <- evt
+	}, EventSessionCreated, EventSessionRemoved, EventSessionRotated, EventUserSessionsInvalidated)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	if s, err = New(opts, e); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var key, token string
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var created *Session
+	if created, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if key, token, err = s.Rotate(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	var rotated *Session
+	if rotated, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.InvalidateUser(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	assertSanitized := func(key string, sess *Session) {
+		switch {
+		case len(sess.Key) > 0 || len(sess.CSRFToken) > 0:
+			t.Fatalf("expected %s payload to be sanitized and received %+v", key, sess)
+		case len(sess.ID) == 0 || sess.UserID != testUser1 || sess.LastUsedAt == 0 || sess.AssuranceLevel != AssuranceSingleFactor:
+			t.Fatalf("invalid %s payload, received %+v", key, sess)
+		}
+	}
+
+	// Rotation continues the existing login, it must not be reported as a session being created or removed
+	for i := 0; i < 3; i++ {
+		var evt events.Event
+		select {
+		case evt = <-received:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for session events")
+		}
+
+		switch v := evt.Value.(type) {
+		case *Session:
+			if evt.Key != EventSessionCreated {
+				t.Fatalf("invalid event, expected <%s> and received <%s>", EventSessionCreated, evt.Key)
+			}
+
+			assertSanitized(evt.Key, v)
+		case SessionRotation:
+			expected := SessionRotation{UserID: testUser1, PreviousSessionID: created.ID, SessionID: rotated.ID}
+			if v != expected {
+				t.Fatalf("invalid %s payload, expected %+v and received %+v", evt.Key, expected, v)
+			}
+		case UserSessions:
+			if len(v.Sessions) != 1 {
+				t.Fatalf("invalid %s payload, expected one session and received %d", evt.Key, len(v.Sessions))
+			}
+
+			assertSanitized(evt.Key, v.Sessions[0])
+		default:
+			t.Fatalf("invalid %s payload type, received %T", evt.Key, v)
+		}
+	}
+
+	select {
+	case evt := <-received:
+		t.Fatalf("unexpected %s event, received %+v", evt.Key, evt.Value)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// legacySession is a session as persisted prior to the expiry index
+type legacySession struct {
+	Session
+}
+
+func (l *legacySession) GetRelationships() (r mojura.Relationships) {
+	r.Append(l.Key)
+	r.Append(l.UserID)
+	return
+}
+
+func TestSessions_Limit(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	evicted := make(chan *Session, 1)
+	e := events.New()
+	e.Subscribe(func(evt events.Event) {
+		evicted <- evt.Value.(*Session)
+	}, EventSessionEvicted)
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if s, err = New(opts, e); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.SetLimit(Limit{Max: 1, Policy: LimitPolicyEvict})
+
+	var key, token string
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Get(key, token); err != mojura.ErrEntryNotFound {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", mojura.ErrEntryNotFound, err)
+	}
+
+	select {
+	case sess := <-evicted:
+		if sess.UserID != testUser1 {
+			t.Fatalf("invalid user match, expected %s and received %s", testUser1, sess.UserID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for eviction event")
+	}
+
+	s.SetLimit(Limit{Max: 1, Policy: LimitPolicyReject})
+	if _, _, err = s.New(testUser1); err != ErrSessionLimitReached {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrSessionLimitReached, err)
+	}
+}
+
+func TestSessions_Limit_expired(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	var opts mojura.Opts
+	opts.Dir = t.TempDir()
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var key, token string
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var sess *Session
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expire the session without purging it
+	sess.LastUsedAt = time.Now().Add(-time.Second * (SessionTimeout + 60)).Unix()
+	if _, err = s.c.Put(sess.ID, sess); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetLimit(Limit{Max: 1, Policy: LimitPolicyReject})
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetLimit(Limit{Max: 2, Policy: LimitPolicyEvict})
+	if _, _, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The active session is kept, the expired session does not count toward the limit
+	if _, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessions_Reauthenticate(t *testing.T) {
+	var (
+		s   *Sessions
+		err error
+	)
+
+	if err = os.MkdirAll("./test_data", 0744); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./test_data")
+
+	var opts mojura.Opts
+	opts.Dir = "./test_data"
+	if s, err = New(opts, events.New()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var key, token string
+	if key, token, err = s.New(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var sess *Session
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if !sess.IsFresh(time.Minute, AssuranceSingleFactor) {
+		t.Fatal("expected new session to be fresh at single-factor")
+	}
+
+	if sess.IsFresh(time.Minute, AssuranceMultiFactor) {
+		t.Fatal("expected new session to not be fresh at multi-factor")
+	}
+
+	if key, token, err = s.Reauthenticate(key, token, AssuranceMultiFactor); err != nil {
+		t.Fatal(err)
+	}
+
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if !sess.IsFresh(time.Minute, AssuranceMultiFactor) {
+		t.Fatal("expected reauthenticated session to be fresh at multi-factor")
+	}
+
+	// Rotation should retain the authentication state
+	if key, token, err = s.Rotate(key, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	} else if sess.AssuranceLevel != AssuranceMultiFactor {
+		t.Fatalf("invalid assurance level, expected %v and received %v", AssuranceMultiFactor, sess.AssuranceLevel)
+	}
+
+	// Reauthenticating at a lower level should not downgrade the session
+	if key, token, err = s.Reauthenticate(key, token, AssuranceSingleFactor); err != nil {
+		t.Fatal(err)
+	}
+
+	if sess, err = s.Get(key, token); err != nil {
+		t.Fatal(err)
+	} else if sess.AssuranceLevel != AssuranceMultiFactor {
+		t.Fatalf("invalid assurance level, expected %v and received %v", AssuranceMultiFactor, sess.AssuranceLevel)
+	}
+}
+
+func TestParseBearer(t *testing.T) {
+	bearer := FormatBearer("key", "token")
+	key, token, err := ParseBearer(bearer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key != "key" || token != "token" {
+		t.Fatalf("invalid pair, expected <key>/<token> and received <%s>/<%s>", key, token)
+	}
+
+	if _, _, err = ParseBearer("not-a-bearer"); err != ErrInvalidBearerToken {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrInvalidBearerToken, err)
+	}
+}
@@ -0,0 +1,13 @@
+package sessions
+
+func makeUserSessions(userID string, ss []*Session) (u UserSessions) {
+	u.UserID = userID
+	u.Sessions = ss
+	return
+}
+
+// UserSessions represents a set of sessions belonging to a user
+type UserSessions struct {
+	UserID   string     `json:"userID"`
+	Sessions []*Session `json:"sessions"`
+}
@@ -1,5 +1,23 @@
 package sessions
 
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
 func makeSessionKey(key, token string) (mapkey string) {
 	return key + "::" + token
 }
+
+func newCSRFToken() (token string) {
+	bs := make([]byte, 32)
+	// Note: crypto/rand.Read never returns an error
+	rand.Read(bs)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+// formatHourBucket will format a unix timestamp as a sortable hour bucket
+func formatHourBucket(unix int64) (bucket string) {
+	return fmt.Sprintf("%012d", unix/3600)
+}
@@ -0,0 +1,70 @@
+package jump
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/vroomy/httpserve"
+
+	"github.com/gdbu/jump/sessions"
+)
+
+func TestJump_RecordAuthentication_rotation(t *testing.T) {
+	j := newTestJump(t)
+	baseURL := newTestServer(t, func(s *httpserve.Serve) error {
+		return s.GET("/step-up", j.NewSetUserIDMW(false, false), func(ctx *httpserve.Context) {
+			if err := j.RecordAuthentication(ctx, sessions.AssuranceMultiFactor); err != nil {
+				ctx.WriteJSON(500, err)
+				return
+			}
+
+			ctx.WriteNoContent()
+		})
+	})
+
+	key, token, err := j.sess.New(testUser1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Privilege changes rotate the session on its next use, before the step-up handler is reached
+	if err = j.sess.MarkForRotation(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequest("GET", baseURL+"/step-up", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: CookieKey, Value: key})
+	req.AddCookie(&http.Cookie{Name: CookieToken, Value: token})
+
+	var res *http.Response
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != 204 {
+		t.Fatalf("invalid status code, expected <%d> and received <%d>", 204, res.StatusCode)
+	}
+
+	var sess *sessions.Session
+	if sess, err = j.sess.Get(getResponseCookie(res, CookieKey), getResponseCookie(res, CookieToken)); err != nil {
+		t.Fatal(err)
+	}
+
+	if sess.AssuranceLevel != sessions.AssuranceMultiFactor {
+		t.Fatalf("invalid assurance level, expected <%s> and received <%s>", sessions.AssuranceMultiFactor, sess.AssuranceLevel)
+	}
+
+	var ss []*sessions.Session
+	if ss, err = j.sess.GetByUserID(testUser1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ss) != 1 {
+		t.Fatalf("invalid sessions, expected one and received %d", len(ss))
+	}
+}
@@ -0,0 +1,79 @@
+package signing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// NewSigner will return a signer for an API key
+// Note: See apikeys.Parse to obtain the key ID and secret from a full API key
+func NewSigner(keyID, secret string) *Signer {
+	var s Signer
+	s.keyID = keyID
+	s.key = DeriveKey(secret)
+	return &s
+}
+
+// Signer signs outgoing requests on behalf of an API key
+type Signer struct {
+	keyID string
+	key   []byte
+}
+
+// Sign will set the signing headers on a request
+// Note: The request body is read and replaced, sign the request after the body has been set
+func (s *Signer) Sign(req *http.Request) (err error) {
+	timestamp := formatTimestamp(time.Now().Unix())
+
+	var nonce string
+	if nonce, err = newNonce(); err != nil {
+		return
+	}
+
+	var canonical string
+	if canonical, err = canonicalize(req, timestamp, nonce); err != nil {
+		return
+	}
+
+	req.Header.Set(HeaderKeyID, s.keyID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(s.key, canonical))
+	return
+}
+
+// Transport will return a round tripper which signs every request before passing it to the provided transport
+// Note: When the provided transport is nil, http.DefaultTransport is used
+func (s *Signer) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripper(func(req *http.Request) (resp *http.Response, err error) {
+		// Round trippers must not modify the provided request
+		req = req.Clone(req.Context())
+		if err = s.Sign(req); err != nil {
+			return
+		}
+
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func newNonce() (nonce string, err error) {
+	bs := make([]byte, 16)
+	if _, err = rand.Read(bs); err != nil {
+		return
+	}
+
+	nonce = hex.EncodeToString(bs)
+	return
+}
@@ -0,0 +1,123 @@
+// Package signing implements HMAC request signing for API keys
+//
+// A signed request carries the public key ID along with an HMAC-SHA256 signature over the
+// canonical request (method, path, query, timestamp, nonce and body hash). The API key itself
+// never leaves the client. The signing key is derived from the key secret as the hex encoded
+// SHA-256 of the secret, which is the value the server stores.
+//
+// Note: The server verifies signatures using the stored secret hash, so anyone able to read the
+// api keys database can forge signed requests. Plain keys cannot be recovered from the hash, so
+// signing trades some protection against a database leak for keeping keys out of transit logs.
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gdbu/errors"
+)
+
+const (
+	// HeaderKeyID is the request header containing the public API key ID
+	HeaderKeyID = "X-Jump-Key-ID"
+	// HeaderTimestamp is the request header containing the unix timestamp the request was signed at
+	HeaderTimestamp = "X-Jump-Timestamp"
+	// HeaderNonce is the request header containing the single-use request nonce
+	HeaderNonce = "X-Jump-Nonce"
+	// HeaderSignature is the request header containing the hex encoded request signature
+	HeaderSignature = "X-Jump-Signature"
+)
+
+const (
+	// MaxBodySize is the largest request body which can be signed
+	MaxBodySize = 10 << 20
+)
+
+const (
+	// ErrMissingHeaders is returned when a request does not contain all signing headers
+	ErrMissingHeaders = errors.Error("request is missing signing headers")
+	// ErrInvalidTimestamp is returned when the signing timestamp cannot be parsed
+	ErrInvalidTimestamp = errors.Error("invalid signing timestamp")
+	// ErrClockSkew is returned when the signing timestamp is outside of the allowed skew
+	ErrClockSkew = errors.Error("signing timestamp is outside of the allowed clock skew")
+	// ErrReplayedNonce is returned when a nonce has already been used
+	ErrReplayedNonce = errors.Error("signing nonce has already been used")
+	// ErrInvalidSignature is returned when a signature does not match the request
+	ErrInvalidSignature = errors.Error("invalid request signature")
+	// ErrBodyTooLarge is returned when a request body exceeds MaxBodySize
+	ErrBodyTooLarge = errors.Error("request body is too large to sign")
+)
+
+// DeriveKey will return the signing key for an API key secret
+func DeriveKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// IsSigned will return whether or not a request carries a signature
+func IsSigned(req *http.Request) bool {
+	return len(req.Header.Get(HeaderSignature)) > 0
+}
+
+// CanonicalRequest will return the string which is signed for a request
+func CanonicalRequest(method, path, query, timestamp, nonce, bodyHash string) string {
+	return strings.Join([]string{strings.ToUpper(method), path, query, timestamp, nonce, bodyHash}, "\n")
+}
+
+// Sign will return the hex encoded signature of a canonical request
+func Sign(key []byte, canonical string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isSignatureMatch will compare a signature to the expected signature in constant time
+func isSignatureMatch(key []byte, canonical, signature string) bool {
+	expected := Sign(key, canonical)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// canonicalize will return the canonical form of a request
+// Note: The request body is read and replaced so it remains available to handlers
+func canonicalize(req *http.Request, timestamp, nonce string) (canonical string, err error) {
+	var bodyHash string
+	if bodyHash, err = hashBody(req); err != nil {
+		return
+	}
+
+	// Encoding the parsed query sorts it by key
+	query := req.URL.Query().Encode()
+	canonical = CanonicalRequest(req.Method, req.URL.EscapedPath(), query, timestamp, nonce, bodyHash)
+	return
+}
+
+func hashBody(req *http.Request) (bodyHash string, err error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if body, err = io.ReadAll(io.LimitReader(req.Body, MaxBodySize+1)); err != nil {
+			return
+		}
+
+		req.Body.Close()
+		if len(body) > MaxBodySize {
+			err = ErrBodyTooLarge
+			return
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	sum := sha256.Sum256(body)
+	bodyHash = hex.EncodeToString(sum[:])
+	return
+}
+
+func formatTimestamp(unix int64) string {
+	return strconv.FormatInt(unix, 10)
+}
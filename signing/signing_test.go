@@ -0,0 +1,103 @@
+package signing
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testKeyID  = "TESTKEYID0000001"
+	testSecret = "TESTSECRET000000000000000000001"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	s := NewSigner(testKeyID, testSecret)
+	v := NewVerifier(DefaultMaxSkew)
+	fn := func(keyID string) ([]byte, error) {
+		return DeriveKey(testSecret), nil
+	}
+
+	req := httptest.NewRequest("POST", "/users?b=2&a=1", strings.NewReader(`{"name":"test"}`))
+	if err := s.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	keyID, err := v.Verify(req, fn)
+	if err != nil {
+		t.Fatal(err)
+	} else if keyID != testKeyID {
+		t.Fatalf("invalid key ID, expected <%s> and received <%s>", testKeyID, keyID)
+	}
+
+	if _, err = v.Verify(req, fn); err != ErrReplayedNonce {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrReplayedNonce, err)
+	}
+
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"name":"test"}` {
+		t.Fatalf("expected body to remain readable, received <%s>", body)
+	}
+}
+
+func TestVerifier_Verify_tampered(t *testing.T) {
+	s := NewSigner(testKeyID, testSecret)
+	v := NewVerifier(DefaultMaxSkew)
+	fn := func(keyID string) ([]byte, error) {
+		return DeriveKey(testSecret), nil
+	}
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"test"}`))
+	if err := s.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Body = io.NopCloser(strings.NewReader(`{"name":"admin"}`))
+	if _, err := v.Verify(req, fn); err != ErrInvalidSignature {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrInvalidSignature, err)
+	}
+
+	req = httptest.NewRequest("GET", "/users", http.NoBody)
+	if err := s.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := time.Now().Add(-DefaultMaxSkew * 2).Unix()
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(stale, 10))
+	if _, err := v.Verify(req, fn); err != ErrClockSkew {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrClockSkew, err)
+	}
+}
+
+func TestVerifier_SetMaxSkew(t *testing.T) {
+	v := NewVerifier(DefaultMaxSkew)
+	fn := func(keyID string) ([]byte, error) {
+		return DeriveKey(testSecret), nil
+	}
+
+	req := httptest.NewRequest("GET", "/users", http.NoBody)
+	if err := NewSigner(testKeyID, testSecret).Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-sign the request with a timestamp outside of the default skew
+	stale := formatTimestamp(time.Now().Add(-DefaultMaxSkew * 2).Unix())
+	canonical, err := canonicalize(req, stale, req.Header.Get(HeaderNonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set(HeaderTimestamp, stale)
+	req.Header.Set(HeaderSignature, Sign(DeriveKey(testSecret), canonical))
+	if _, err = v.Verify(req, fn); err != ErrClockSkew {
+		t.Fatalf("invalid error, expected <%v> and received <%v>", ErrClockSkew, err)
+	}
+
+	v.SetMaxSkew(DefaultMaxSkew * 3)
+	if _, err = v.Verify(req, fn); err != nil {
+		t.Fatal(err)
+	}
+}
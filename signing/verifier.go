@@ -0,0 +1,120 @@
+package signing
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSkew is the default maximum difference between the signing timestamp and the server clock
+const DefaultMaxSkew = time.Minute * 5
+
+// KeyFunc returns the signing key for a key ID
+type KeyFunc func(keyID string) (key []byte, err error)
+
+// NewVerifier will return a new verifier allowing the provided clock skew
+func NewVerifier(maxSkew time.Duration) *Verifier {
+	var v Verifier
+	v.maxSkew = maxSkew
+	v.nonces = make(map[string]time.Time)
+	return &v
+}
+
+// Verifier verifies signed requests and rejects replayed nonces
+// Note: Nonces are cached in memory for twice the max skew, requests older than the skew are rejected outright
+type Verifier struct {
+	maxSkew time.Duration
+
+	mux         sync.Mutex
+	nonces      map[string]time.Time
+	lastCleanup time.Time
+}
+
+// SetMaxSkew will set the maximum difference allowed between the signing timestamp and the server clock
+func (v *Verifier) SetMaxSkew(maxSkew time.Duration) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.maxSkew = maxSkew
+}
+
+// Verify will verify the signature of a request and return the key ID which signed it
+func (v *Verifier) Verify(req *http.Request, fn KeyFunc) (keyID string, err error) {
+	keyID = req.Header.Get(HeaderKeyID)
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+	if len(keyID) == 0 || len(timestamp) == 0 || len(nonce) == 0 || len(signature) == 0 {
+		err = ErrMissingHeaders
+		return
+	}
+
+	var unix int64
+	if unix, err = strconv.ParseInt(timestamp, 10, 64); err != nil {
+		err = ErrInvalidTimestamp
+		return
+	}
+
+	now := time.Now()
+	maxSkew := v.getMaxSkew()
+	if skew := now.Sub(time.Unix(unix, 0)); skew > maxSkew || skew < -maxSkew {
+		err = ErrClockSkew
+		return
+	}
+
+	var key []byte
+	if key, err = fn(keyID); err != nil {
+		return
+	}
+
+	var canonical string
+	if canonical, err = canonicalize(req, timestamp, nonce); err != nil {
+		return
+	}
+
+	if !isSignatureMatch(key, canonical, signature) {
+		err = ErrInvalidSignature
+		return
+	}
+
+	// Nonces are only recorded for valid signatures so unauthenticated callers cannot fill the cache
+	if !v.useNonce(keyID+":"+nonce, now) {
+		err = ErrReplayedNonce
+		return
+	}
+
+	return
+}
+
+func (v *Verifier) getMaxSkew() time.Duration {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	return v.maxSkew
+}
+
+// useNonce will record a nonce and return false if it has already been used
+func (v *Verifier) useNonce(nonce string, now time.Time) (ok bool) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.cleanup(now)
+	if _, ok = v.nonces[nonce]; ok {
+		return false
+	}
+
+	v.nonces[nonce] = now.Add(v.maxSkew * 2)
+	return true
+}
+
+// cleanup will remove the nonces which can no longer be replayed within the allowed skew
+func (v *Verifier) cleanup(now time.Time) {
+	if now.Sub(v.lastCleanup) < v.maxSkew {
+		return
+	}
+
+	v.lastCleanup = now
+	for nonce, expiresAt := range v.nonces {
+		if now.After(expiresAt) {
+			delete(v.nonces, nonce)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package sso
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gdbu/errors"
@@ -54,6 +55,7 @@ func New(opts mojura.Opts) (cc *Controller, err error) {
 	c.out = mojura.NewLogger()
 	c.updateCh = make(chan struct{}, 1)
 	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.wg.Add(1)
 	go c.expirationScan()
 	// Assign pointer reference to our controller
 	cc = &c
@@ -71,6 +73,7 @@ type Controller struct {
 
 	ctx    context.Context
 	cancel func()
+	wg     sync.WaitGroup
 }
 
 // New will insert a new Entry to the back-end
@@ -247,6 +250,8 @@ func (c *Controller) ReadTransaction(ctx context.Context, fn func(txn *Transacti
 // Close will close the controller and it's underlying dependencies
 func (c *Controller) Close() (err error) {
 	c.cancel()
+	// Wait for the expiration scan to exit before closing the data layer
+	c.wg.Wait()
 	// Since we only have one dependency, we can just call this func directly
 	return c.m.Close()
 }
@@ -438,6 +443,7 @@ func (c *Controller) multiLogin(txn *mojura.Transaction[*Entry], loginCode strin
 }
 
 func (c *Controller) expirationScan() {
+	defer c.wg.Done()
 	var (
 		next *Entry
 		err  error
@@ -455,23 +461,33 @@ func (c *Controller) expirationScan() {
 		case nil:
 		case mojura.ErrEntryNotFound:
 			// Wait for new update to come through update channel
-			<-c.updateCh
+			c.waitForUpdate()
 			continue
 
 		default:
-			c.out.Error(fmt.Sprintf("error getting next to expire: %v", err))
+			if c.ctx.Err() == nil {
+				c.out.Error(fmt.Sprintf("error getting next to expire: %v", err))
+			}
+
 			// Wait for new update to come through update channel
-			<-c.updateCh
+			c.waitForUpdate()
 			continue
 		}
 
-		if wait(next.ExpiresAt, c.updateCh) {
+		if wait(c.ctx, next.ExpiresAt, c.updateCh) {
 			continue
 		}
 
-		if _, err = c.Delete(context.Background(), next.ID); err != nil {
+		if _, err = c.Delete(c.ctx, next.ID); err != nil && c.ctx.Err() == nil {
 			c.out.Error(fmt.Sprintf("error deleting next to expire: %v", err))
 			continue
 		}
 	}
 }
+
+func (c *Controller) waitForUpdate() {
+	select {
+	case <-c.ctx.Done():
+	case <-c.updateCh:
+	}
+}
@@ -1,6 +1,7 @@
 package sso
 
 import (
+	"context"
 	"time"
 
 	"github.com/mojura/mojura"
@@ -31,7 +32,7 @@ func yesFilter(_ string) (ok bool, err error) {
 	return true, nil
 }
 
-func wait(waitUntil time.Time, ch chan struct{}) (cancelled bool) {
+func wait(ctx context.Context, waitUntil time.Time, ch chan struct{}) (cancelled bool) {
 	now := time.Now()
 	duration := waitUntil.Sub(now)
 	if duration <= 0 {
@@ -39,12 +40,14 @@ func wait(waitUntil time.Time, ch chan struct{}) (cancelled bool) {
 	}
 
 	timer := time.NewTimer(duration)
+	defer timer.Stop()
 	select {
 	case <-timer.C:
 		return false
 	case <-ch:
 		return true
-
+	case <-ctx.Done():
+		return true
 	}
 }
 
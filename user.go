@@ -69,8 +69,18 @@ func (j *Jump) UpdateEmail(userID, newEmail string) (updated *users.User, err er
 }
 
 // UpdatePassword is the update password handler
+// Note: All existing sessions for the user are invalidated, call NewSession to issue a fresh
+// session to the current client
 func (j *Jump) UpdatePassword(userID, newPassword string) (updated *users.User, err error) {
-	return j.usrs.UpdatePassword(userID, newPassword)
+	if updated, err = j.usrs.UpdatePassword(userID, newPassword); err != nil {
+		return
+	}
+
+	if err = j.sess.InvalidateUser(userID); err != nil {
+		return
+	}
+
+	return
 }
 
 // EnableUser will enable a user
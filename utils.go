@@ -2,8 +2,9 @@ package jump
 
 import (
 	"fmt"
+	"net"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/gdbu/errors"
 	"github.com/gdbu/jump/permissions"
@@ -19,14 +20,31 @@ const (
 	PermRWD = permissions.ActionRead | permissions.ActionWrite | permissions.ActionDelete
 )
 
+const bearerPrefix = "Bearer "
+
 // NewResourceKey will return a new resource key from a given resource name and resource ID
+// The resource ID is escaped (see permissions.EscapeResourceID), so an ID containing the key separator or
+// wildcard cannot address the descendants of another resource. Use NewChildResourceKey for hierarchical keys.
 // Note: Providing an empty resourceID will treat the resource as a grouping resource (No ID association)
+// Note: Grants persisted for IDs containing "/", "*" or "%" before escaping was introduced are stored under the
+// unescaped key and are no longer matched, they must be re-granted (or the stored keys rewritten) using this func
 func NewResourceKey(resourceName, resourceID string) (resourceKey string) {
 	if len(resourceID) == 0 {
 		return resourceName
 	}
 
-	return fmt.Sprintf("%s::%s", resourceName, resourceID)
+	return fmt.Sprintf("%s::%s", resourceName, permissions.EscapeResourceID(resourceID))
+}
+
+// NewChildResourceKey will create a hierarchical resource key nested beneath a parent resource key
+// Note: Child resources inherit the permissions of their ancestors, see permissions.Permissions.Can
+func NewChildResourceKey(parentKey, resourceName, resourceID string) (resourceKey string) {
+	return parentKey + permissions.KeySeparator + NewResourceKey(resourceName, resourceID)
+}
+
+// NewWildcardResourceKey will create a resource key which grants access to all descendants of a parent resource key
+func NewWildcardResourceKey(parentKey string) (resourceKey string) {
+	return parentKey + permissions.KeySeparator + permissions.Wildcard
 }
 
 func getUserID(ctx *httpserve.Context) (userID string, err error) {
@@ -38,45 +56,34 @@ func getUserID(ctx *httpserve.Context) (userID string, err error) {
 	return
 }
 
-func getAPIKey(ctx *httpserve.Context) (apiKey string) {
-	q := ctx.Request().URL.Query()
-
-	if apiKey = q.Get("apiKey"); len(apiKey) > 0 {
-		return
-	}
-
-	var (
-		vals []string
-		ok   bool
-	)
+func getAPIKeyFromQuery(req *http.Request) (apiKey string) {
+	return req.URL.Query().Get("apiKey")
+}
 
-	if vals, ok = ctx.Request().Header["X-Api-Key"]; !ok {
-		return
-	}
+func getAPIKeyFromHeader(req *http.Request) (apiKey string) {
+	return req.Header.Get("X-Api-Key")
+}
 
-	if len(vals) == 0 {
+func getBearerToken(req *http.Request) (bearer string, ok bool) {
+	auth := req.Header.Get("Authorization")
+	if len(auth) <= len(bearerPrefix) || !strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
 		return
 	}
 
-	apiKey = vals[0]
+	bearer = strings.TrimSpace(auth[len(bearerPrefix):])
+	ok = len(bearer) > 0
 	return
 }
 
-func newCookie(host, name, value string, expires time.Time) (c http.Cookie) {
-	c.Domain = host
-	c.Name = name
-	c.Value = value
-	c.Expires = expires
-	c.Path = "/"
-	return
-}
-
-func setCookie(host, name, value string) (c http.Cookie) {
-	return newCookie(host, name, value, time.Now().AddDate(0, 0, 7))
-}
+// getRemoteIP will return the IP address of the peer which made the request
+func getRemoteIP(req *http.Request) (ip string) {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		// Remote address does not contain a port
+		return req.RemoteAddr
+	}
 
-func unsetCookie(host, name, value string) (c http.Cookie) {
-	return newCookie(host, name, value, time.Now().AddDate(-1, 0, 0))
+	return host
 }
 
 func getCookieValue(req *http.Request, name string) (value string, err error) {
@@ -0,0 +1,33 @@
+package jump
+
+import (
+	"testing"
+
+	"github.com/gdbu/jump/permissions"
+)
+
+func TestNewResourceKey(t *testing.T) {
+	j := newTestJump(t)
+	if _, err := j.grps.AddGroups(testUser1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.perm.SetPermissions(NewResourceKey("files", "alice"), "alice", permissions.ActionRead); err != nil {
+		t.Fatal(err)
+	}
+
+	if !j.perm.Can(testUser1, NewResourceKey("files", "alice"), permissions.ActionRead) {
+		t.Fatal("expected access to the granted resource")
+	}
+
+	// IDs cannot fabricate a parent which inherits the grant
+	for _, resourceID := range []string{"alice/secret", "alice/*"} {
+		if j.perm.Can(testUser1, NewResourceKey("files", resourceID), permissions.ActionRead) {
+			t.Fatalf("expected no access to <%s>", resourceID)
+		}
+	}
+
+	if !j.perm.Can(testUser1, NewChildResourceKey(NewResourceKey("files", "alice"), "doc", "7"), permissions.ActionRead) {
+		t.Fatal("expected access to the child resource")
+	}
+}